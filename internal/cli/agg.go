@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/voidarchive/Gator/internal/database"
+)
+
+const (
+	defaultAggWorkers   = 4
+	defaultAggBatchSize = 10
+
+	// How often to look for due feeds when the interval is longer, so feeds
+	// put off by a busy host or a failure are picked up on time
+	claimPollInterval = 5 * time.Second
+)
+
+// HandlerAgg refreshes every feed once per interval. Due feeds are claimed in
+// batches and fetched in parallel by a fixed pool of workers.
+func HandlerAgg(s *State, cmd Command) error {
+	workers := s.Cfg.AggWorkers
+	if workers <= 0 {
+		workers = defaultAggWorkers
+	}
+	batchSize := s.Cfg.AggBatchSize
+	if batchSize <= 0 {
+		batchSize = defaultAggBatchSize
+	}
+
+	var interval string
+	for i := 0; i < len(cmd.Args); i++ {
+		switch arg := cmd.Args[i]; arg {
+		case "--workers", "--batch":
+			if i+1 >= len(cmd.Args) {
+				return fmt.Errorf("%s requires a value", arg)
+			}
+			i++
+			n, err := strconv.Atoi(cmd.Args[i])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("%s must be a positive number", arg)
+			}
+			if arg == "--workers" {
+				workers = n
+			} else {
+				batchSize = n
+			}
+		default:
+			if interval != "" {
+				return fmt.Errorf("usage: agg <time_between_reqs> [--workers N] [--batch N]")
+			}
+			interval = arg
+		}
+	}
+	if interval == "" {
+		return fmt.Errorf("usage: agg <time_between_reqs> [--workers N] [--batch N]")
+	}
+
+	timeBetweenReqs, err := time.ParseDuration(interval)
+	if err != nil {
+		return fmt.Errorf("invalid duration: %v", err)
+	}
+
+	fmt.Printf("Collecting feeds every %v with %d workers\n", timeBetweenReqs, workers)
+
+	fetcher, err := newFeedFetcher(s.Cfg)
+	if err != nil {
+		return err
+	}
+	fetcher.limitHosts(s.Cfg.HostRateLimit, s.Cfg.HostMaxInFlight)
+	feeds := make(chan database.Feed, batchSize)
+	for i := 0; i < workers; i++ {
+		go scrapeWorker(s, fetcher, feeds)
+	}
+
+	ticker := time.NewTicker(min(timeBetweenReqs, claimPollInterval))
+	defer ticker.Stop()
+
+	// Run immediately first, then wait for ticker
+	for ; ; <-ticker.C {
+		err := claimDueFeeds(s, feeds, timeBetweenReqs, batchSize, time.Now())
+		if err != nil {
+			fmt.Printf("Error scraping feeds: %v\n", err)
+			// Continue the loop even if there's an error
+		}
+	}
+}
+
+// claimDueFeeds hands every feed not fetched within the interval to the
+// workers, a batch at a time. Sending blocks while the workers are busy, so
+// feeds are only claimed as fast as they can be fetched. Times are compared
+// by the database's clock; only how long ago the pass started is passed in.
+func claimDueFeeds(s *State, feeds chan<- database.Feed, interval time.Duration, batchSize int, passStart time.Time) error {
+	ctx := context.Background()
+	for {
+		batch, err := s.DB.ClaimFeedsToFetch(ctx, database.ClaimFeedsToFetchParams{
+			PassAgeSecs:  time.Since(passStart).Seconds(),
+			IntervalSecs: interval.Seconds(),
+			BatchSize:    int32(batchSize),
+		})
+		if err != nil {
+			return fmt.Errorf("error claiming feeds to fetch: %v", err)
+		}
+		for _, feed := range batch {
+			feeds <- feed
+		}
+		if len(batch) < batchSize {
+			return nil
+		}
+	}
+}
+
+func scrapeWorker(s *State, fetcher *feedFetcher, feeds <-chan database.Feed) {
+	for feed := range feeds {
+		if err := scrapeFeedSafely(s, fetcher, feed); err != nil {
+			fmt.Printf("Error scraping feed %s: %v\n", feed.Name, err)
+		}
+	}
+}
+
+// scrapeFeedSafely turns a panic while handling one feed into an error, so a
+// single bad feed can't take a worker down with it
+func scrapeFeedSafely(s *State, fetcher *feedFetcher, feed database.Feed) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return scrapeFeed(context.Background(), s, fetcher, feed)
+}
@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// AtomFeed is the metadata of an Atom feed, from the root element's
+// attributes and the children that aren't entries
+type AtomFeed struct {
+	Base      string
+	Lang      string
+	Logo      string
+	Icon      string
+	Generator string
+	Title     AtomText
+	Subtitle  AtomText
+	Links     []AtomLink
+	Updated   string
+	Authors   []AtomPerson
+}
+
+// AtomEntry only takes elements in the Atom namespace, so extensions sharing
+// their names (media:content, itunes:summary, itunes:author) are left alone
+type AtomEntry struct {
+	Base    string     `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	ID      string     `xml:"http://www.w3.org/2005/Atom id"`
+	Title   AtomText   `xml:"http://www.w3.org/2005/Atom title"`
+	Links   []AtomLink `xml:"http://www.w3.org/2005/Atom link"`
+	Summary AtomText   `xml:"http://www.w3.org/2005/Atom summary"`
+	Content AtomText   `xml:"http://www.w3.org/2005/Atom content"`
+
+	Published  string         `xml:"http://www.w3.org/2005/Atom published"`
+	Updated    string         `xml:"http://www.w3.org/2005/Atom updated"`
+	Authors    []AtomPerson   `xml:"http://www.w3.org/2005/Atom author"`
+	Categories []AtomCategory `xml:"http://www.w3.org/2005/Atom category"`
+	mediaRSS
+}
+
+type AtomPerson struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email"`
+}
+
+type AtomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+type AtomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// AtomText is an Atom text construct, which may carry plain text, escaped
+// HTML or inline XHTML depending on its type attribute
+type AtomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+func (t AtomText) String() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(t.Inner)
+	}
+	return strings.TrimSpace(t.Text)
+}
+
+// alternateLink picks the entry's permalink: rel="alternate" (the default
+// when rel is omitted), preferring HTML over other representations
+func alternateLink(links []AtomLink) string {
+	var fallback string
+	for _, link := range links {
+		if link.Rel != "" && link.Rel != "alternate" {
+			continue
+		}
+		if link.Type == "" || link.Type == "text/html" {
+			return link.Href
+		}
+		if fallback == "" {
+			fallback = link.Href
+		}
+	}
+	return fallback
+}
+
+// atomReader reads an Atom document, decoding its entries one at a time
+type atomReader struct {
+	decoder *xml.Decoder
+	feed    AtomFeed
+}
+
+func (r *atomReader) header() *ParsedFeed {
+	a := &r.feed
+	feed := &ParsedFeed{
+		Title:       a.Title.String(),
+		Link:        alternateLink(a.Links),
+		Description: a.Subtitle.String(),
+		Language:    a.Lang,
+		Image:       strings.TrimSpace(a.Logo),
+		Generator:   strings.TrimSpace(a.Generator),
+		Base:        a.Base,
+		Updated:     strings.TrimSpace(a.Updated),
+	}
+	if feed.Image == "" {
+		feed.Image = strings.TrimSpace(a.Icon)
+	}
+	return feed
+}
+
+func (r *atomReader) next() (*FeedItem, error) {
+	return nextXMLItem(r.decoder, func(se xml.StartElement) (*FeedItem, error) {
+		if se.Name.Space != atomNamespace {
+			return nil, r.decoder.Skip()
+		}
+
+		a := &r.feed
+		var err error
+		switch se.Name.Local {
+		case "entry":
+			var entry AtomEntry
+			if err := r.decoder.DecodeElement(&entry, &se); err != nil {
+				return nil, err
+			}
+			item := entry.toFeedItem(a.Authors)
+			return &item, nil
+		case "title":
+			err = r.decoder.DecodeElement(&a.Title, &se)
+		case "subtitle":
+			err = r.decoder.DecodeElement(&a.Subtitle, &se)
+		case "link":
+			var link AtomLink
+			err = r.decoder.DecodeElement(&link, &se)
+			a.Links = append(a.Links, link)
+		case "updated":
+			err = r.decoder.DecodeElement(&a.Updated, &se)
+		case "author":
+			var author AtomPerson
+			err = r.decoder.DecodeElement(&author, &se)
+			a.Authors = append(a.Authors, author)
+		case "logo":
+			err = r.decoder.DecodeElement(&a.Logo, &se)
+		case "icon":
+			err = r.decoder.DecodeElement(&a.Icon, &se)
+		case "generator":
+			err = r.decoder.DecodeElement(&a.Generator, &se)
+		default:
+			err = r.decoder.Skip()
+		}
+		return nil, err
+	})
+}
+
+// toFeedItem converts an entry; entries without their own author inherit
+// the feed's
+func (entry *AtomEntry) toFeedItem(feedAuthors []AtomPerson) FeedItem {
+	pubDate := entry.Published
+	if pubDate == "" {
+		pubDate = entry.Updated
+	}
+
+	var enclosures []FeedEnclosure
+	for _, link := range entry.Links {
+		if link.Rel == "enclosure" && link.Href != "" {
+			enclosures = append(enclosures, FeedEnclosure{
+				URL:    link.Href,
+				Type:   link.Type,
+				Length: parseLength(link.Length),
+			})
+		}
+	}
+
+	people := entry.Authors
+	if len(people) == 0 {
+		people = feedAuthors
+	}
+	var authors []FeedAuthor
+	for _, person := range people {
+		if name := strings.TrimSpace(person.Name); name != "" {
+			authors = append(authors, FeedAuthor{Name: name, Email: strings.TrimSpace(person.Email)})
+		}
+	}
+
+	var categories []string
+	for _, category := range entry.Categories {
+		if category.Label != "" {
+			categories = append(categories, category.Label)
+		} else if category.Term != "" {
+			categories = append(categories, category.Term)
+		}
+	}
+
+	return FeedItem{
+		GUID:        strings.TrimSpace(entry.ID),
+		Base:        entry.Base,
+		Title:       entry.Title.String(),
+		Link:        alternateLink(entry.Links),
+		Description: entry.Summary.String(),
+		Content:     entry.Content.String(),
+		PubDate:     strings.TrimSpace(pubDate),
+		Authors:     authors,
+		Categories:  categories,
+		Enclosures:  enclosures,
+		Media:       entry.toFeedMedia(),
+	}
+}
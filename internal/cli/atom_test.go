@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAtomReader(t *testing.T) {
+	tests := []struct {
+		name      string
+		doc       string
+		wantFeed  ParsedFeed
+		wantItems []FeedItem
+	}{
+		{
+			name: "Atom 1.0",
+			doc: `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xml:lang="en" xmlns:media="http://search.yahoo.com/mrss/">
+	<title type="html">Tom &amp;amp; Jerry</title>
+	<subtitle>A blog</subtitle>
+	<link rel="self" href="https://example.org/feed.atom"/>
+	<link href="https://example.org/blog/"/>
+	<updated>2024-05-01T10:00:00Z</updated>
+	<author><name>Tom</name><email>tom@example.org</email></author>
+	<generator uri="https://gohugo.io/">Hugo</generator>
+	<icon>/favicon.ico</icon>
+	<entry>
+		<id> urn:uuid:1 </id>
+		<title>First</title>
+		<link rel="alternate" type="application/json" href="posts/1.json"/>
+		<link rel="alternate" type="text/html" href="posts/1"/>
+		<link rel="enclosure" type="audio/mpeg" length="1234" href="ep1.mp3"/>
+		<link rel="replies" href="posts/1#comments"/>
+		<summary>The first</summary>
+		<content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><a href="/about">About</a></div></content>
+		<published>2024-05-01T09:00:00Z</published>
+		<updated>2024-05-02T09:00:00Z</updated>
+		<category term="go" label="Go"/>
+		<category term="news"/>
+		<category/>
+		<media:thumbnail url="https://cdn.example.org/1.jpg"/>
+	</entry>
+	<entry xml:base="https://other.example/a/">
+		<id>urn:uuid:2</id>
+		<title type="html">&lt;b&gt;Second&lt;/b&gt;</title>
+		<link href="2.html"/>
+		<updated>2024-05-03T09:00:00Z</updated>
+		<author><name> Jerry </name></author>
+		<content type="html">&lt;img src="2.jpg"&gt;</content>
+	</entry>
+</feed>`,
+			wantFeed: ParsedFeed{
+				Title:       "Tom & Jerry",
+				Link:        "https://example.org/blog/",
+				Description: "A blog",
+				Language:    "en",
+				Image:       "https://example.org/favicon.ico",
+				Generator:   "Hugo",
+				Updated:     "2024-05-01T10:00:00Z",
+				Encoding:    "utf-8",
+			},
+			wantItems: []FeedItem{
+				{
+					GUID:        "urn:uuid:1",
+					Title:       "First",
+					Link:        "https://example.org/blog/posts/1",
+					Description: "The first",
+					Content:     `<div xmlns="http://www.w3.org/1999/xhtml"><a href="https://example.org/about">About</a></div>`,
+					PubDate:     "2024-05-01T09:00:00Z",
+					Authors:     []FeedAuthor{{Name: "Tom", Email: "tom@example.org"}},
+					Categories:  []string{"Go", "news"},
+					Enclosures:  []FeedEnclosure{{URL: "https://example.org/blog/ep1.mp3", Type: "audio/mpeg", Length: 1234}},
+					Media: []FeedMedia{{
+						URL:       "https://cdn.example.org/1.jpg",
+						Medium:    "image",
+						Thumbnail: "https://cdn.example.org/1.jpg",
+					}},
+				},
+				{
+					GUID:    "urn:uuid:2",
+					Base:    "https://other.example/a/",
+					Title:   "<b>Second</b>",
+					Link:    "https://other.example/a/2.html",
+					Content: `<img src="https://other.example/a/2.jpg">`,
+					PubDate: "2024-05-03T09:00:00Z",
+					Authors: []FeedAuthor{{Name: "Jerry"}},
+				},
+			},
+		},
+		{
+			name: "logo wins over icon and metadata after the entries",
+			doc: `<feed xmlns="http://www.w3.org/2005/Atom" xml:base="https://example.org/base/">
+				<icon>icon.png</icon><logo>logo.png</logo>
+				<entry><id>1</id><link href="one"/></entry>
+				<title>Late title</title></feed>`,
+			wantFeed: ParsedFeed{
+				Title:    "Late title",
+				Image:    "https://example.org/base/logo.png",
+				Base:     "https://example.org/base/",
+				Encoding: "utf-8",
+			},
+			wantItems: []FeedItem{{GUID: "1", Link: "https://example.org/base/one"}},
+		},
+		{
+			// Podcast feeds mix these in; the feed's author should still apply
+			name: "extensions sharing names with entry elements",
+			doc: `<feed xmlns="http://www.w3.org/2005/Atom" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd"
+				xmlns:media="http://search.yahoo.com/mrss/">
+				<author><name>Tom</name></author>
+				<entry>
+					<id>1</id>
+					<title>Title</title>
+					<summary>Summary</summary>
+					<link href="https://example.org/1"/>
+					<itunes:summary>iTunes summary</itunes:summary>
+					<itunes:author>iTunes author</itunes:author>
+					<media:title>Media title</media:title>
+					<media:content url="https://cdn.example.org/1.mp4" medium="video"/>
+				</entry>
+			</feed>`,
+			wantFeed: ParsedFeed{Encoding: "utf-8"},
+			wantItems: []FeedItem{{
+				GUID:        "1",
+				Title:       "Title",
+				Link:        "https://example.org/1",
+				Description: "Summary",
+				Authors:     []FeedAuthor{{Name: "Tom"}},
+				Media:       []FeedMedia{{URL: "https://cdn.example.org/1.mp4", Medium: "video"}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, items, err := readFeed(t, tt.doc, "application/atom+xml")
+			if err != nil {
+				t.Fatalf("reading feed: %v", err)
+			}
+			if !reflect.DeepEqual(*feed, tt.wantFeed) {
+				t.Errorf("feed = %+v, want %+v", *feed, tt.wantFeed)
+			}
+			if len(items) != len(tt.wantItems) {
+				t.Fatalf("got %d items, want %d", len(items), len(tt.wantItems))
+			}
+			for i := range items {
+				if !reflect.DeepEqual(items[i], tt.wantItems[i]) {
+					t.Errorf("item %d = %+v, want %+v", i, items[i], tt.wantItems[i])
+				}
+			}
+		})
+	}
+}
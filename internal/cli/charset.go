@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var xmlEncodingPattern = regexp.MustCompile(`^\s*<\?xml[^>]*?encoding\s*=\s*["']([A-Za-z0-9._:-]+)["']`)
+
+// windows1252High maps bytes 0x80-0x9F, the only range where Windows-1252
+// differs from ISO-8859-1. Zero entries are unassigned in Windows-1252.
+var windows1252High = [32]rune{
+	0x20AC, 0, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0, 0x017D, 0,
+	0, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0, 0x017E, 0x0178,
+}
+
+// iso885915 lists the eight code points where Latin-9 replaced Latin-1 characters
+var iso885915 = map[byte]rune{
+	0xA4: 0x20AC, 0xA6: 0x0160, 0xA8: 0x0161, 0xB4: 0x017D,
+	0xB8: 0x017E, 0xBC: 0x0152, 0xBD: 0x0153, 0xBE: 0x0178,
+}
+
+// decodeCharset transcodes a feed to UTF-8 as it's read and reports the
+// encoding it was in. The charset from the HTTP Content-Type takes precedence
+// over the XML declaration, as RFC 7303 specifies.
+func decodeCharset(r io.Reader, contentType string) (io.Reader, string, error) {
+	br := bufio.NewReader(r)
+	if bom, _ := br.Peek(3); bytes.Equal(bom, []byte("\xEF\xBB\xBF")) {
+		br.Discard(3)
+	}
+
+	label := ""
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		label = params["charset"]
+	}
+	if label == "" {
+		// The declaration has to come first, so the start of the document is enough
+		prefix, _ := br.Peek(512)
+		if m := xmlEncodingPattern.FindSubmatch(prefix); m != nil {
+			label = string(m[1])
+		}
+	}
+
+	encoding := normalizeCharset(label)
+	switch encoding {
+	case "utf-8":
+		return br, encoding, nil
+	case "iso-8859-1", "windows-1252":
+		// Feeds labelled Latin-1 are nearly always really Windows-1252 (curly
+		// quotes, dashes), and browsers decode them that way too
+		return &singleByteReader{r: br, decode: func(b byte) rune {
+			if b >= 0x80 && b <= 0x9F && windows1252High[b-0x80] != 0 {
+				return windows1252High[b-0x80]
+			}
+			return rune(b)
+		}}, encoding, nil
+	case "iso-8859-15":
+		return &singleByteReader{r: br, decode: func(b byte) rune {
+			if r, ok := iso885915[b]; ok {
+				return r
+			}
+			return rune(b)
+		}}, encoding, nil
+	default:
+		return nil, encoding, fmt.Errorf("unsupported charset: %s", label)
+	}
+}
+
+func normalizeCharset(label string) string {
+	switch strings.ToLower(strings.TrimSpace(label)) {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return "utf-8"
+	case "iso-8859-1", "iso8859-1", "iso_8859-1", "latin1", "latin-1", "l1", "cp819":
+		return "iso-8859-1"
+	case "windows-1252", "cp1252", "x-cp1252":
+		return "windows-1252"
+	case "iso-8859-15", "iso8859-15", "iso_8859-15", "latin-9", "latin9":
+		return "iso-8859-15"
+	default:
+		return strings.ToLower(strings.TrimSpace(label))
+	}
+}
+
+// singleByteReader transcodes a single-byte encoding to UTF-8
+type singleByteReader struct {
+	r       io.Reader
+	decode  func(byte) rune
+	raw     [1024]byte
+	decoded []byte
+	out     []byte // the part of decoded not read yet
+}
+
+func (s *singleByteReader) Read(p []byte) (int, error) {
+	if len(s.out) == 0 {
+		n, err := s.r.Read(s.raw[:])
+		if n == 0 {
+			return 0, err
+		}
+		s.decoded = s.decoded[:0]
+		for _, b := range s.raw[:n] {
+			if b < utf8.RuneSelf {
+				s.decoded = append(s.decoded, b)
+				continue
+			}
+			s.decoded = utf8.AppendRune(s.decoded, s.decode(b))
+		}
+		s.out = s.decoded
+	}
+	n := copy(p, s.out)
+	s.out = s.out[n:]
+	return n, nil
+}
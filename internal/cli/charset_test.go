@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDecodeCharset(t *testing.T) {
+	tests := []struct {
+		name         string
+		contentType  string
+		in           string
+		want         string
+		wantEncoding string
+	}{
+		{"no charset", "application/rss+xml", "<rss>caf\xc3\xa9</rss>", "<rss>caf\xc3\xa9</rss>", "utf-8"},
+		{"utf-8 BOM", "", "\xEF\xBB\xBF<rss/>", "<rss/>", "utf-8"},
+		{"ascii is utf-8", "text/xml; charset=us-ascii", "<rss/>", "<rss/>", "utf-8"},
+		{"latin-1 declaration", "", `<?xml version="1.0" encoding="ISO-8859-1"?><t>caf` + "\xe9</t>",
+			`<?xml version="1.0" encoding="ISO-8859-1"?><t>café</t>`, "iso-8859-1"},
+		{"latin-1 read as windows-1252", "", "<?xml version='1.0' encoding='latin1'?><t>\x93hi\x94 \x96 \x80</t>",
+			"<?xml version='1.0' encoding='latin1'?><t>“hi” – €</t>", "iso-8859-1"},
+		{"windows-1252 header", "text/xml; charset=windows-1252", "<t>\x85</t>", "<t>…</t>", "windows-1252"},
+		{"header beats declaration", "text/xml; charset=iso-8859-15",
+			`<?xml version="1.0" encoding="utf-8"?><t>` + "\xa4</t>", `<?xml version="1.0" encoding="utf-8"?><t>€</t>`, "iso-8859-15"},
+		{"latin-9 keeps the rest of latin-1", "text/xml; charset=latin9", "<t>\xe9\xa4</t>", "<t>é€</t>", "iso-8859-15"},
+		{"bad header charset falls back to declaration", "text/xml; charset", `<?xml encoding="cp1252"?>` + "\x99",
+			`<?xml encoding="cp1252"?>™`, "windows-1252"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, encoding, err := decodeCharset(strings.NewReader(tt.in), tt.contentType)
+			if err != nil {
+				t.Fatalf("decodeCharset: %v", err)
+			}
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("reading: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if encoding != tt.wantEncoding {
+				t.Errorf("encoding = %q, want %q", encoding, tt.wantEncoding)
+			}
+		})
+	}
+}
+
+func TestDecodeCharsetUnsupported(t *testing.T) {
+	_, encoding, err := decodeCharset(strings.NewReader("<rss/>"), "text/xml; charset=Shift_JIS")
+	if err == nil {
+		t.Fatal("expected an error for an unsupported charset")
+	}
+	if encoding != "shift_jis" {
+		t.Errorf("encoding = %q, want shift_jis", encoding)
+	}
+}
+
+// TestDecodeCharsetStreaming checks that transcoding doesn't depend on how
+// the input is split up, both byte by byte and across the internal buffer
+func TestDecodeCharsetStreaming(t *testing.T) {
+	in := bytes.Repeat([]byte("caf\xe9 \x93quoted\x94 "), 500)
+	want := strings.Repeat("café “quoted” ", 500)
+
+	for name, r := range map[string]io.Reader{
+		"whole":       bytes.NewReader(in),
+		"byte a time": iotest.OneByteReader(bytes.NewReader(in)),
+		"half chunks": iotest.HalfReader(bytes.NewReader(in)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			decoded, _, err := decodeCharset(r, "text/xml; charset=windows-1252")
+			if err != nil {
+				t.Fatalf("decodeCharset: %v", err)
+			}
+			// Read into a small buffer so decoded output is split up as well
+			got, err := io.ReadAll(iotest.OneByteReader(decoded))
+			if err != nil {
+				t.Fatalf("reading: %v", err)
+			}
+			if string(got) != want {
+				t.Errorf("got %d bytes that differ from the %d expected", len(got), len(want))
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Layouts are tried in order after normalizeTimeString has dropped the
+// weekday and turned any zone into a numeric offset. Fractional seconds are
+// accepted by time.Parse after any seconds field without being spelled out.
+var timeLayouts = []string{
+	// RFC 822/1123 and the many ways feeds get them slightly wrong
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04 -0700",
+	"2 January 2006 15:04:05 -0700",
+	"2 January 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006 15:04",
+	"2 January 2006 15:04:05",
+	"2 Jan 2006",
+	"2 January 2006",
+
+	// ISO 8601 / RFC 3339
+	time.RFC3339,
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04-0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+
+	// US style and ctime-like dates
+	"Jan 2 2006 15:04:05 -0700",
+	"Jan 2 2006 15:04:05",
+	"Jan 2 2006 3:04 PM",
+	"January 2 2006 3:04 PM",
+	"Jan 2 2006",
+	"January 2 2006",
+	"Jan 2 15:04:05 -0700 2006",
+	"Jan 2 15:04:05 2006",
+	"01/02/2006 15:04:05",
+	"01/02/2006",
+}
+
+// zoneOffsets maps the zone abbreviations seen in feeds to their UTC offset
+// in minutes. time.Parse only knows the offset of abbreviations matching the
+// local zone and silently treats the rest as UTC.
+var zoneOffsets = map[string]int{
+	"UT": 0, "UTC": 0, "GMT": 0, "Z": 0, "WET": 0,
+	"EST": -5 * 60, "EDT": -4 * 60,
+	"CST": -6 * 60, "CDT": -5 * 60,
+	"MST": -7 * 60, "MDT": -6 * 60,
+	"PST": -8 * 60, "PDT": -7 * 60,
+	"AKST": -9 * 60, "AKDT": -8 * 60,
+	"HST": -10 * 60,
+	"AST": -4 * 60, "ADT": -3 * 60,
+	"NST": -(3*60 + 30), "NDT": -(2*60 + 30),
+	"BST": 1 * 60, "IST": 5*60 + 30, "WEST": 1 * 60,
+	"CET": 1 * 60, "CEST": 2 * 60, "MET": 1 * 60, "MEST": 2 * 60,
+	"EET": 2 * 60, "EEST": 3 * 60, "MSK": 3 * 60,
+	"PKT": 5 * 60, "SGT": 8 * 60, "HKT": 8 * 60, "AWST": 8 * 60,
+	"JST": 9 * 60, "KST": 9 * 60,
+	"ACST": 9*60 + 30, "ACDT": 10*60 + 30,
+	"AEST": 10 * 60, "AEDT": 11 * 60,
+	"NZST": 12 * 60, "NZDT": 13 * 60,
+}
+
+var (
+	zoneWithOffsetPattern = regexp.MustCompile(`^(?:GMT|UTC|UT)([+-])(\d{1,2})(?::?(\d{2}))?$`)
+	colonOffsetPattern    = regexp.MustCompile(`^([+-]\d{2}):(\d{2})$`)
+	weekdayPattern        = regexp.MustCompile(`^(?i)(mon|tue|wed|thu|fri|sat|sun)[a-z]*\.?(,\s*|\s+)`)
+)
+
+// parseFeedTime understands the date formats feeds actually publish, which
+// are far looser than the RFC 822 and RFC 3339 their specs ask for
+func parseFeedTime(timeStr string) (time.Time, error) {
+	normalized := normalizeTimeString(timeStr)
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, normalized); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unable to parse time: %s", timeStr)
+}
+
+func normalizeTimeString(s string) string {
+	// The weekday adds nothing and is frequently wrong, misspelled or missing
+	// the space after its comma
+	s = weekdayPattern.ReplaceAllString(strings.TrimSpace(s), "")
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	// Commas land in different places from feed to feed ("Jan 2, 2006",
+	// "2 Jan, 2006"), so the layouts don't use any
+	for i := range fields {
+		fields[i] = strings.Trim(fields[i], ",")
+	}
+
+	last := len(fields) - 1
+	zone := strings.ToUpper(strings.Trim(fields[last], "()"))
+	if offset, ok := zoneOffsets[zone]; ok && len(fields) > 1 {
+		fields[last] = formatOffset(offset)
+	} else if m := zoneWithOffsetPattern.FindStringSubmatch(zone); m != nil {
+		hours, minutes := m[2], m[3]
+		if len(hours) == 1 {
+			hours = "0" + hours
+		}
+		if minutes == "" {
+			minutes = "00"
+		}
+		fields[last] = m[1] + hours + minutes
+	} else if m := colonOffsetPattern.FindStringSubmatch(fields[last]); m != nil && len(fields) > 1 {
+		fields[last] = m[1] + m[2]
+	}
+
+	// "2 Jan 2006 15:04:05 GMT -0500": some feeds send both a name and an offset
+	if len(fields) > 2 {
+		if _, ok := zoneOffsets[strings.ToUpper(fields[last-1])]; ok && strings.ContainsAny(fields[last][:1], "+-") {
+			fields = append(fields[:last-1], fields[last])
+		}
+	}
+
+	return strings.Join(fields, " ")
+}
+
+func formatOffset(minutes int) string {
+	sign := "+"
+	if minutes < 0 {
+		sign = "-"
+		minutes = -minutes
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, minutes/60, minutes%60)
+}
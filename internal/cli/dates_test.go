@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseFeedTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string // RFC 3339, in UTC
+	}{
+		// RFC 822/1123 and near misses
+		{"Mon, 02 Jan 2006 15:04:05 GMT", "2006-01-02T15:04:05Z"},
+		{"Mon,02 Jan 2006 15:04:05 GMT", "2006-01-02T15:04:05Z"},
+		{"Monday, 02 Jan 2006 15:04:05 +0000", "2006-01-02T15:04:05Z"},
+		{"Tue, 2 Jan 2006 15:04:05 GMT", "2006-01-02T15:04:05Z"},
+		{"Mon, 02 Jan 2006 15:04:05 -0700", "2006-01-02T22:04:05Z"},
+		{"Mon, 02 Jan 2006 15:04:05 -07:00", "2006-01-02T22:04:05Z"},
+		{"Mon, 02 Jan 2006 15:04:05 EST", "2006-01-02T20:04:05Z"},
+		{"Mon, 02 Jan 2006 15:04:05 PDT", "2006-01-02T22:04:05Z"},
+		{"Mon, 02 Jan 2006 15:04:05 CEST", "2006-01-02T13:04:05Z"},
+		{"Mon, 02 Jan 2006 15:04:05 GMT+2", "2006-01-02T13:04:05Z"},
+		{"Mon, 02 Jan 2006 15:04:05 UTC-05:30", "2006-01-02T20:34:05Z"},
+		{"Mon, 02 Jan 2006 15:04:05 GMT -0500", "2006-01-02T20:04:05Z"},
+		{"Mon, 02 Jan 2006 15:04:05 (GMT)", "2006-01-02T15:04:05Z"},
+		{"Mon, 02 Jan 2006 15:04 GMT", "2006-01-02T15:04:00Z"},
+		{"Mon, 02 Jan 06 15:04:05 GMT", "2006-01-02T15:04:05Z"},
+		{"Mon, 02 January 2006 15:04:05 GMT", "2006-01-02T15:04:05Z"},
+		{"02 Jan 2006 15:04:05", "2006-01-02T15:04:05Z"},
+		{"02 Jan, 2006", "2006-01-02T00:00:00Z"},
+		{"  Mon, 02 Jan 2006 15:04:05 GMT  ", "2006-01-02T15:04:05Z"},
+		{"Mon, 02 Jan 2006 15:04:05.123 GMT", "2006-01-02T15:04:05.123Z"},
+
+		// ISO 8601 / RFC 3339
+		{"2006-01-02T15:04:05Z", "2006-01-02T15:04:05Z"},
+		{"2006-01-02T15:04:05.999999Z", "2006-01-02T15:04:05.999999Z"},
+		{"2006-01-02T15:04:05+01:00", "2006-01-02T14:04:05Z"},
+		{"2006-01-02T15:04:05+0100", "2006-01-02T14:04:05Z"},
+		{"2006-01-02T15:04Z", "2006-01-02T15:04:00Z"},
+		{"2006-01-02T15:04:05", "2006-01-02T15:04:05Z"},
+		{"2006-01-02 15:04:05", "2006-01-02T15:04:05Z"},
+		{"2006-01-02 15:04:05 -0700", "2006-01-02T22:04:05Z"},
+		{"2006-01-02", "2006-01-02T00:00:00Z"},
+
+		// US style and ctime-like
+		{"Jan 2, 2006", "2006-01-02T00:00:00Z"},
+		{"January 2, 2006 3:04 PM", "2006-01-02T15:04:00Z"},
+		{"Mon Jan 2 15:04:05 2006", "2006-01-02T15:04:05Z"},
+		{"Mon Jan 2 15:04:05 -0700 2006", "2006-01-02T22:04:05Z"},
+		{"01/02/2006", "2006-01-02T00:00:00Z"},
+	}
+	for _, tt := range tests {
+		got, err := parseFeedTime(tt.in)
+		if err != nil {
+			t.Errorf("parseFeedTime(%q): %v", tt.in, err)
+			continue
+		}
+		if s := got.UTC().Format(time.RFC3339Nano); s != tt.want {
+			t.Errorf("parseFeedTime(%q) = %s, want %s", tt.in, s, tt.want)
+		}
+	}
+}
+
+func TestParseFeedTimeInvalid(t *testing.T) {
+	for _, in := range []string{"", "   ", "Mon,", "yesterday", "32 Jan 2006", "2006-13-01", "Mon, 02 Foo 2006 15:04:05 GMT"} {
+		if got, err := parseFeedTime(in); err == nil {
+			t.Errorf("parseFeedTime(%q) = %v, want an error", in, got)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// feedCandidate is a feed found while looking at a web page. Stream is set,
+// and has to be closed, once the candidate has been fetched and is being
+// parsed; ETag and LastModified then come from the same response.
+type feedCandidate struct {
+	URL          string
+	Title        string
+	Stream       *feedStream
+	ETag         string
+	LastModified string
+}
+
+// feedLinkTypes are the <link type> values that advertise a feed
+var feedLinkTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+}
+
+// commonFeedPaths are tried, relative to the site root, when a page doesn't
+// advertise its feeds
+var commonFeedPaths = []string{"/feed", "/rss.xml", "/atom.xml", "/feed.xml", "/index.xml", "/feed.json"}
+
+var (
+	linkTagPattern = regexp.MustCompile(`(?is)<(link|base)\b[^>]*>`)
+	attrPattern    = regexp.MustCompile(`(?s)([a-zA-Z:-]+)\s*=\s*("[^"]*"|'[^']*'|[^\s"'>]+)`)
+)
+
+// locateFeed works out which feed the user means: the URL itself when it
+// serves a feed, otherwise one of the feeds the page points to. The returned
+// candidate has always been fetched, and its Stream is open. Every request
+// is made with opts.
+func locateFeed(ctx context.Context, fetcher *feedFetcher, rawURL string, opts fetchOptions) (feedCandidate, error) {
+	candidates, err := feedCandidates(ctx, fetcher, rawURL, opts)
+	if err != nil {
+		return feedCandidate{}, err
+	}
+	if len(candidates) == 0 {
+		return feedCandidate{}, fmt.Errorf("no feed found at %s", rawURL)
+	}
+	candidate, err := chooseFeed(candidates)
+	if err != nil {
+		return feedCandidate{}, err
+	}
+
+	if candidate.Stream == nil {
+		result, err := fetcher.fetchFeed(ctx, feedRequest{URL: candidate.URL, Options: opts})
+		if err != nil {
+			return feedCandidate{}, fmt.Errorf("%s is not a valid feed: %v", candidate.URL, err)
+		}
+		candidate.Stream = result.Stream
+		candidate.ETag, candidate.LastModified = result.ETag, result.LastModified
+	}
+	return candidate, nil
+}
+
+// feedCandidates fetches rawURL and, when it turns out to be a web page,
+// looks for feeds in its <link> tags and then at the usual paths. When rawURL
+// is a feed itself, it's the only candidate and its Stream is open.
+func feedCandidates(ctx context.Context, fetcher *feedFetcher, rawURL string, opts fetchOptions) ([]feedCandidate, error) {
+	result, err := fetcher.fetchURL(ctx, feedRequest{URL: rawURL, Options: opts})
+	if err != nil {
+		return nil, err
+	}
+	body := &peekedBody{Reader: bufio.NewReader(result.Body), Closer: result.Body}
+	if !isHTML(body, result.ContentType) {
+		stream, err := newFeedStream(body, result.ContentType, rawURL)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a valid feed: %v", rawURL, err)
+		}
+		return []feedCandidate{{
+			URL:          rawURL,
+			Title:        stream.Feed().Title,
+			Stream:       stream,
+			ETag:         result.ETag,
+			LastModified: result.LastModified,
+		}}, nil
+	}
+
+	page, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		return nil, err
+	}
+	candidates := findFeedLinks(page, rawURL)
+	if len(candidates) > 0 {
+		return candidates, nil
+	}
+	return probeFeedPaths(ctx, fetcher, rawURL, opts), nil
+}
+
+// peekedBody is a response body read through a buffer, so its start can be
+// looked at without consuming it
+type peekedBody struct {
+	*bufio.Reader
+	io.Closer
+}
+
+func isHTML(body *peekedBody, contentType string) bool {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if mediaType == "text/html" || mediaType == "application/xhtml+xml" {
+			return true
+		}
+	}
+	start, _ := body.Peek(512)
+	start = bytes.ToLower(bytes.TrimSpace(start))
+	return bytes.HasPrefix(start, []byte("<!doctype html")) || bytes.HasPrefix(start, []byte("<html"))
+}
+
+// findFeedLinks collects <link rel="alternate"> tags with a feed type,
+// resolved against the page's <base href> when it has one
+func findFeedLinks(body []byte, pageURL string) []feedCandidate {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+	var candidates []feedCandidate
+	seen := make(map[string]bool)
+	for _, tag := range linkTagPattern.FindAllSubmatch(body, -1) {
+		attrs := parseAttrs(tag[0])
+		if strings.EqualFold(string(tag[1]), "base") {
+			if href := attrs["href"]; href != "" {
+				base = resolveBase(base, href)
+			}
+			continue
+		}
+
+		if !hasToken(attrs["rel"], "alternate") || !feedLinkTypes[strings.ToLower(attrs["type"])] {
+			continue
+		}
+		if attrs["href"] == "" {
+			continue
+		}
+		feedURL := resolveURL(base, attrs["href"])
+		if seen[feedURL] {
+			continue
+		}
+		seen[feedURL] = true
+		candidates = append(candidates, feedCandidate{URL: feedURL, Title: attrs["title"]})
+	}
+	return candidates
+}
+
+func parseAttrs(tag []byte) map[string]string {
+	attrs := make(map[string]string)
+	for _, m := range attrPattern.FindAllSubmatch(tag, -1) {
+		name := strings.ToLower(string(m[1]))
+		value := strings.Trim(string(m[2]), `"'`)
+		if _, ok := attrs[name]; !ok {
+			attrs[name] = html.UnescapeString(strings.TrimSpace(value))
+		}
+	}
+	return attrs
+}
+
+func hasToken(list, token string) bool {
+	for _, field := range strings.Fields(list) {
+		if strings.EqualFold(field, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// probeFeedPaths tries the paths feeds usually live at and keeps the ones
+// that actually parse as feeds
+func probeFeedPaths(ctx context.Context, fetcher *feedFetcher, pageURL string, opts fetchOptions) []feedCandidate {
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+
+	var candidates []feedCandidate
+	for _, path := range commonFeedPaths {
+		feedURL := (&url.URL{Scheme: page.Scheme, User: page.User, Host: page.Host, Path: path}).String()
+		result, err := fetcher.fetchFeed(ctx, feedRequest{URL: feedURL, Options: opts})
+		if err != nil || result.NotModified {
+			continue
+		}
+		// Only the chosen feed is read in full, after fetching it again
+		title := result.Stream.Feed().Title
+		result.Stream.Close()
+		candidates = append(candidates, feedCandidate{URL: feedURL, Title: title})
+	}
+	return candidates
+}
+
+// chooseFeed asks the user to pick when there's more than one candidate
+func chooseFeed(candidates []feedCandidate) (feedCandidate, error) {
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+
+	fmt.Printf("Found %d feeds:\n", len(candidates))
+	for i, candidate := range candidates {
+		if candidate.Title != "" {
+			fmt.Printf("  %d) %s - %s\n", i+1, candidate.Title, candidate.URL)
+		} else {
+			fmt.Printf("  %d) %s\n", i+1, candidate.URL)
+		}
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Printf("Choose a feed [1-%d]: ", len(candidates))
+		line, err := reader.ReadString('\n')
+		choice, convErr := strconv.Atoi(strings.TrimSpace(line))
+		if convErr == nil && choice >= 1 && choice <= len(candidates) {
+			return candidates[choice-1], nil
+		}
+		if err != nil {
+			return feedCandidate{}, fmt.Errorf("no feed chosen; pass one of the URLs above instead")
+		}
+		fmt.Printf("Please enter a number between 1 and %d\n", len(candidates))
+	}
+}
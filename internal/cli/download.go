@@ -0,0 +1,266 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/voidarchive/Gator/internal/database"
+)
+
+func HandlerDownload(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) != 0 {
+		return fmt.Errorf("usage: download")
+	}
+	ctx := context.Background()
+
+	dir, err := s.Cfg.DownloadPath()
+	if err != nil {
+		return fmt.Errorf("error resolving download directory: %v", err)
+	}
+
+	episodes, err := s.DB.GetEpisodesToDownload(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("error getting episodes to download: %v", err)
+	}
+
+	// Episodes are fetched with the same timeouts, proxy and TLS settings as
+	// feeds, and with their feed's own settings
+	fetcher, err := newFeedFetcher(s.Cfg)
+	if err != nil {
+		return err
+	}
+	feedOptions := make(map[uuid.UUID]fetchOptions)
+
+	downloaded := 0
+	for _, episode := range episodes {
+		dest := filepath.Join(dir, sanitizeFileName(episode.FeedName), episodeFileName(episode))
+		fmt.Printf("Downloading %s: %s\n", episode.FeedName, episode.PostTitle)
+
+		opts, ok := feedOptions[episode.FeedID]
+		if !ok {
+			opts, err = loadFetchOptions(ctx, s, episode.FeedID)
+			if err != nil {
+				return err
+			}
+			feedOptions[episode.FeedID] = opts
+		}
+
+		size, err := downloadFile(ctx, fetcher, episode.Url, dest, enclosureOptions(opts, episode.FeedUrl, episode.Url))
+		if err != nil {
+			// Leave the partial file in place so the next run can resume it
+			fmt.Printf("Error downloading %s: %v\n", episode.Url, err)
+			continue
+		}
+
+		_, err = s.DB.CreateDownload(ctx, database.CreateDownloadParams{
+			ID:          uuid.New(),
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+			EnclosureID: episode.EnclosureID,
+			Path:        dest,
+			Size:        size,
+		})
+		if err != nil {
+			return fmt.Errorf("error recording download: %v", err)
+		}
+		downloaded++
+		fmt.Printf("Saved %s (%s)\n", dest, formatBytes(size))
+	}
+
+	// Enforce each feed's "keep last N episodes" policy
+	stale, err := s.DB.GetDownloadsToPrune(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("error getting downloads to prune: %v", err)
+	}
+	for _, download := range stale {
+		if err := os.Remove(download.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			fmt.Printf("Error removing %s: %v\n", download.Path, err)
+			continue
+		}
+		if err := s.DB.DeleteDownload(ctx, download.ID); err != nil {
+			return fmt.Errorf("error deleting download record: %v", err)
+		}
+		fmt.Printf("Removed old episode %s\n", download.Path)
+	}
+
+	fmt.Printf("Downloaded %d episodes, removed %d\n", downloaded, len(stale))
+	return nil
+}
+
+func HandlerKeep(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) < 2 {
+		return fmt.Errorf("usage: keep <feed-url> <episodes|all>")
+	}
+	feedURL := cmd.Args[0]
+	ctx := context.Background()
+
+	var keep sql.NullInt32
+	if cmd.Args[1] != "all" {
+		n, err := strconv.Atoi(cmd.Args[1])
+		if err != nil || n <= 0 {
+			return fmt.Errorf("episodes must be a positive number or \"all\"")
+		}
+		keep = sql.NullInt32{Int32: int32(n), Valid: true}
+	}
+
+	feed, err := s.DB.GetFeedByUrl(ctx, feedURL)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("feed not found")
+		}
+		return fmt.Errorf("error getting feed: %v", err)
+	}
+
+	err = s.DB.SetFeedKeepEpisodes(ctx, database.SetFeedKeepEpisodesParams{
+		ID:           feed.ID,
+		KeepEpisodes: keep,
+	})
+	if err != nil {
+		return fmt.Errorf("error setting episode policy: %v", err)
+	}
+
+	if keep.Valid {
+		fmt.Printf("Keeping the last %d episodes of %s\n", keep.Int32, feed.Name)
+	} else {
+		fmt.Printf("Keeping all episodes of %s\n", feed.Name)
+	}
+	return nil
+}
+
+// enclosureOptions are the feed's fetch settings as they apply to one of its
+// enclosures. Headers and credentials are only sent to the feed's own host
+// and its subdomains, not to whatever CDN the episodes happen to live on.
+func enclosureOptions(opts fetchOptions, feedURL, enclosureURL string) fetchOptions {
+	feed, err := url.Parse(feedURL)
+	if err != nil {
+		return fetchOptions{Proxy: opts.Proxy}
+	}
+	enclosure, err := url.Parse(enclosureURL)
+	if err != nil {
+		return fetchOptions{Proxy: opts.Proxy}
+	}
+	feedHost, host := strings.ToLower(feed.Hostname()), strings.ToLower(enclosure.Hostname())
+	if host != feedHost && !strings.HasSuffix(host, "."+feedHost) {
+		return fetchOptions{Proxy: opts.Proxy}
+	}
+	return opts
+}
+
+// downloadFile saves fileURL to dest, writing to dest+".part" first so an
+// interrupted download can be resumed with an HTTP Range request. A server
+// that stops sending for longer than read_timeout fails the download.
+func downloadFile(ctx context.Context, fetcher *feedFetcher, fileURL, dest string, opts fetchOptions) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return 0, fmt.Errorf("error creating directory: %v", err)
+	}
+	partPath := dest + ".part"
+
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := fetcher.newRequest(ctx, fileURL, opts)
+	if err != nil {
+		return 0, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	// Episodes can be big, so unlike feeds there's no limit on the whole download
+	client := *fetcher.client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		dropHeadersOffHost(req, via, opts)
+		return nil
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("error making request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		flags |= os.O_APPEND
+	case http.StatusOK:
+		// Server ignored the range (or there was nothing to resume), start over
+		flags |= os.O_TRUNC
+		offset = 0
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file already holds the whole thing
+		if err := os.Rename(partPath, dest); err != nil {
+			return 0, fmt.Errorf("error finishing download: %v", err)
+		}
+		return offset, nil
+	default:
+		return 0, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	file, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("error opening file: %v", err)
+	}
+	written, err := io.Copy(file, resp.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error writing file: %v", err)
+	}
+
+	if err := os.Rename(partPath, dest); err != nil {
+		return 0, fmt.Errorf("error finishing download: %v", err)
+	}
+	return offset + written, nil
+}
+
+func episodeFileName(episode database.GetEpisodesToDownloadRow) string {
+	ext := ""
+	if u, err := url.Parse(episode.Url); err == nil {
+		ext = path.Ext(u.Path)
+	}
+	if ext == "" && episode.MimeType.Valid {
+		if exts, err := mime.ExtensionsByType(episode.MimeType.String); err == nil && len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+	// The enclosure ID keeps episodes with identical titles from clobbering each other
+	return sanitizeFileName(episode.PostTitle) + "-" + episode.EnclosureID.String()[:8] + ext
+}
+
+func sanitizeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r < 32, strings.ContainsRune(`/\:*?"<>|`, r):
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.Trim(strings.TrimSpace(name), ".")
+
+	if runes := []rune(name); len(runes) > 100 {
+		name = string(runes[:100])
+	}
+	if name == "" {
+		return "untitled"
+	}
+	return name
+}
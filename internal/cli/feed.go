@@ -0,0 +1,294 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/url"
+	"time"
+)
+
+// ParsedFeed is the format-independent view of a feed's own metadata; its
+// items are read one at a time from a feedStream
+type ParsedFeed struct {
+	Title       string
+	Link        string
+	Description string
+	Language    string
+	Image       string
+	Generator   string
+	Base        string   // xml:base, if the feed sets one
+	Updated     string   // lastBuildDate or equivalent, used when items have no date
+	Encoding    string   // charset the document was transcoded from
+	Repairs     []string // fixes the lenient parser had to make, if any
+}
+
+type FeedItem struct {
+	GUID        string
+	Base        string
+	Title       string
+	Link        string
+	Description string
+	Content     string
+	PubDate     string
+	Authors     []FeedAuthor
+	Categories  []string
+	Enclosures  []FeedEnclosure
+	Media       []FeedMedia
+}
+
+type FeedAuthor struct {
+	Name  string
+	Email string
+}
+
+// FeedEnclosure is an attached media file, along with the podcast metadata
+// that describes the episode it belongs to
+type FeedEnclosure struct {
+	URL      string
+	Type     string
+	Length   int64
+	Duration int // seconds
+	Episode  int
+	Season   int
+	Image    string
+}
+
+func (f *ParsedFeed) lastBuildTime() (time.Time, bool) {
+	if f.Updated == "" {
+		return time.Time{}, false
+	}
+	t, err := parseFeedTime(f.Updated)
+	return t, err == nil
+}
+
+const (
+	atomNamespace = "http://www.w3.org/2005/Atom"
+	xmlNamespace  = "http://www.w3.org/XML/1998/namespace"
+)
+
+// feedReader parses one feed format an item at a time
+type feedReader interface {
+	// header returns the feed's own metadata as far as it has been read
+	header() *ParsedFeed
+	// next returns the next item, or io.EOF after the last one
+	next() (*FeedItem, error)
+}
+
+// feedStream yields a feed's items as they're parsed, so a feed is never
+// held in memory all at once and reading can stop as soon as the rest of it
+// isn't wanted. The first item has already been read once the stream is
+// open, which makes sure the body really is a feed.
+type feedStream struct {
+	body      io.Closer
+	reader    feedReader
+	sanitizer *sanitizingReader // nil for JSON Feed
+	feedURL   string
+	encoding  string
+
+	siteBase  *url.URL // what item links are relative to, worked out at the first item
+	items     int
+	truncated string // why the feed ended early, if it was cut short
+	pending   *FeedItem
+	err       error
+}
+
+func newFeedStream(body io.ReadCloser, contentType, feedURL string) (*feedStream, error) {
+	decoded, encoding, err := decodeCharset(body, contentType)
+	if err != nil {
+		body.Close()
+		return nil, parseError(err)
+	}
+
+	stream := &feedStream{body: body, feedURL: feedURL, encoding: encoding}
+	doc := bufio.NewReader(decoded)
+	if isJSONFeed(doc, contentType) {
+		stream.reader, err = newJSONFeedReader(doc)
+	} else {
+		stream.sanitizer = newSanitizingReader(doc)
+		stream.reader, err = newXMLFeedReader(stream.sanitizer)
+	}
+	if err != nil {
+		body.Close()
+		return nil, parseError(err)
+	}
+
+	first, err := stream.read()
+	switch {
+	case err == io.EOF:
+		stream.err = err
+	case err != nil:
+		body.Close()
+		return nil, err
+	default:
+		stream.pending = &first
+	}
+	return stream, nil
+}
+
+// Next returns the next item in the feed, or io.EOF once there are no more
+func (s *feedStream) Next() (FeedItem, error) {
+	if s.pending != nil {
+		item := *s.pending
+		s.pending = nil
+		return item, nil
+	}
+	if s.err != nil {
+		return FeedItem{}, s.err
+	}
+	item, err := s.read()
+	if err != nil {
+		s.err = err
+		return FeedItem{}, err
+	}
+	return item, nil
+}
+
+func (s *feedStream) read() (FeedItem, error) {
+	item, err := s.reader.next()
+	if err == io.EOF {
+		return FeedItem{}, io.EOF
+	}
+	if err != nil {
+		// A feed that breaks off partway, or goes on past max_feed_size_mb,
+		// still has the items before that point
+		if s.items > 0 && (isSyntaxError(err) || isTooLarge(err)) {
+			s.truncated = fmt.Sprintf("kept %d items before %v", s.items, err)
+			return FeedItem{}, io.EOF
+		}
+		return FeedItem{}, parseError(err)
+	}
+
+	if s.items == 0 {
+		s.siteBase = s.reader.header().resolveURLs(s.feedURL)
+	}
+	s.items++
+	item.Title = html.UnescapeString(item.Title)
+	item.Description = html.UnescapeString(item.Description)
+	item.resolveURLs(s.siteBase)
+	return *item, nil
+}
+
+// Feed returns the feed's own metadata. Elements that come after the items
+// are only included once Next has returned io.EOF.
+func (s *feedStream) Feed() *ParsedFeed {
+	feed := s.reader.header()
+	feed.Title = html.UnescapeString(feed.Title)
+	feed.Description = html.UnescapeString(feed.Description)
+	feed.Encoding = s.encoding
+	if s.sanitizer != nil {
+		feed.Repairs = s.sanitizer.repairs()
+	}
+	if s.truncated != "" {
+		feed.Repairs = append(feed.Repairs, s.truncated)
+	}
+	feed.resolveURLs(s.feedURL)
+	return feed
+}
+
+// Close stops reading the feed, releasing the connection it came over
+func (s *feedStream) Close() error {
+	return s.body.Close()
+}
+
+// parseError marks err as the feed failing to parse, unless reading it
+// failed in the first place, in which case that failure's class is kept
+func parseError(err error) error {
+	var fetchErr *fetchError
+	if errors.As(err, &fetchErr) {
+		return &fetchError{Class: fetchErr.Class, Permanent: fetchErr.Permanent, Err: err}
+	}
+	return &fetchError{Class: errorClassParse, Permanent: true, Err: err}
+}
+
+func isTooLarge(err error) bool {
+	var fetchErr *fetchError
+	return errors.As(err, &fetchErr) && fetchErr.Class == errorClassSize
+}
+
+// isJSONFeed trusts the Content-Type when the server sends a JSON one, and
+// otherwise sniffs the body since plenty of servers label JSON Feed as text/plain
+func isJSONFeed(doc *bufio.Reader, contentType string) bool {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if mediaType == "application/feed+json" || mediaType == "application/json" {
+			return true
+		}
+	}
+	start, _ := doc.Peek(512)
+	return bytes.HasPrefix(bytes.TrimSpace(start), []byte("{"))
+}
+
+// newXMLFeedReader reads up to the root element to decide which format
+// we're dealing with. Documents are always decoded non-strictly, since by
+// the time a strict decoder found a problem it would be too late to start over.
+func newXMLFeedReader(doc io.Reader) (feedReader, error) {
+	decoder := xml.NewDecoder(doc)
+	// decodeCharset has already transcoded the body to UTF-8, so whatever the
+	// XML declaration claims can be read as-is
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	decoder.Strict = false
+	decoder.AutoClose = lenientAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	var root xml.StartElement
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("error reading XML: %w", err)
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			root = se
+			break
+		}
+	}
+
+	switch {
+	case root.Name.Local == "rss":
+		return &rssReader{decoder: decoder}, nil
+	case root.Name.Local == "feed" && root.Name.Space == atomNamespace:
+		return &atomReader{decoder: decoder, feed: AtomFeed{
+			Base: xmlAttr(root, xmlNamespace, "base"),
+			Lang: xmlAttr(root, xmlNamespace, "lang"),
+		}}, nil
+	case root.Name.Local == "RDF" && root.Name.Space == rdfNamespace:
+		return &rdfReader{decoder: decoder}, nil
+	default:
+		return nil, fmt.Errorf("unsupported feed format: <%s>", root.Name.Local)
+	}
+}
+
+// nextXMLItem reads on until element turns one of the elements it meets into
+// an item. element decodes or skips every element it's given, except those
+// it wants to descend into, whose children it's given next.
+func nextXMLItem(decoder *xml.Decoder, element func(xml.StartElement) (*FeedItem, error)) (*FeedItem, error) {
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		item, err := element(se)
+		if err != nil || item != nil {
+			return item, err
+		}
+	}
+}
+
+func xmlAttr(se xml.StartElement, space, local string) string {
+	for _, attr := range se.Attr {
+		if attr.Name.Space == space && attr.Name.Local == local {
+			return attr.Value
+		}
+	}
+	return ""
+}
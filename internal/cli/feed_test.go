@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testFeedURL = "https://example.com/feed"
+
+// readFeed parses doc the way a fetched feed is parsed, returning its
+// metadata and every item in it
+func readFeed(t *testing.T, doc, contentType string) (*ParsedFeed, []FeedItem, error) {
+	t.Helper()
+	stream, err := newFeedStream(io.NopCloser(strings.NewReader(doc)), contentType, testFeedURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer stream.Close()
+
+	var items []FeedItem
+	for {
+		item, err := stream.Next()
+		if err == io.EOF {
+			return stream.Feed(), items, nil
+		}
+		if err != nil {
+			return nil, items, err
+		}
+		items = append(items, item)
+	}
+}
+
+func TestFeedStream(t *testing.T) {
+	tests := []struct {
+		name         string
+		doc          string
+		contentType  string
+		wantTitles   []string
+		wantEncoding string
+		wantRepairs  []string
+	}{
+		{
+			name:         "JSON Feed served as text/plain",
+			doc:          ` {"version": "https://jsonfeed.org/version/1.1", "items": [{"id": "1", "title": "One"}]}`,
+			contentType:  "text/plain",
+			wantTitles:   []string{"One"},
+			wantEncoding: "utf-8",
+		},
+		{
+			name:         "transcoded",
+			doc:          `<?xml version="1.0" encoding="windows-1252"?><rss><channel><item><title>` + "\x93Hi\x94</title></item></channel></rss>",
+			contentType:  "application/xml",
+			wantTitles:   []string{"“Hi”"},
+			wantEncoding: "windows-1252",
+		},
+		{
+			name:         "repaired",
+			doc:          "<rss><channel><item><title>Q&A &mdash; \x01</title></item></channel></rss>",
+			contentType:  "application/rss+xml",
+			wantTitles:   []string{"Q&A —"},
+			wantEncoding: "utf-8",
+			wantRepairs: []string{
+				"replaced 1 HTML entities",
+				"escaped 1 bare ampersands",
+				"removed 1 invalid control characters",
+			},
+		},
+		{
+			name:         "cut short",
+			doc:          "<rss><channel><item><title>One</title></item><item><title>Two</title></item><item><title>Thr",
+			contentType:  "application/rss+xml",
+			wantTitles:   []string{"One", "Two"},
+			wantEncoding: "utf-8",
+			wantRepairs:  []string{"kept 2 items before XML syntax error on line 1: unexpected EOF"},
+		},
+		{
+			name:         "cut short JSON Feed",
+			doc:          `{"version": "https://jsonfeed.org/version/1.1", "items": [{"id": "1", "title": "One"}, {"id": "2", "ti`,
+			contentType:  "application/feed+json",
+			wantTitles:   []string{"One"},
+			wantEncoding: "utf-8",
+			wantRepairs:  []string{"kept 1 items before unexpected EOF"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, items, err := readFeed(t, tt.doc, tt.contentType)
+			if err != nil {
+				t.Fatalf("reading feed: %v", err)
+			}
+			var titles []string
+			for _, item := range items {
+				titles = append(titles, strings.TrimSpace(item.Title))
+			}
+			if !reflect.DeepEqual(titles, tt.wantTitles) {
+				t.Errorf("titles = %q, want %q", titles, tt.wantTitles)
+			}
+			if feed.Encoding != tt.wantEncoding {
+				t.Errorf("encoding = %q, want %q", feed.Encoding, tt.wantEncoding)
+			}
+			if !reflect.DeepEqual(feed.Repairs, tt.wantRepairs) {
+				t.Errorf("repairs = %q, want %q", feed.Repairs, tt.wantRepairs)
+			}
+		})
+	}
+}
+
+func TestFeedStreamInvalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		doc         string
+		contentType string
+		wantErr     string
+	}{
+		{"HTML page", "<!DOCTYPE html><html><body>Not a feed</body></html>", "text/html", "unsupported feed format: <html>"},
+		{"empty", "", "application/rss+xml", "EOF"},
+		{"broken before any item", "<rss><channel><title>T</title><item><title>One", "application/rss+xml", "unexpected EOF"},
+		{"unsupported charset", "<rss/>", "application/rss+xml; charset=koi8-r", "unsupported charset: koi8-r"},
+		{"other JSON", `{"data": [1, 2, 3]}`, "application/json", "unsupported JSON Feed version"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, items, err := readFeed(t, tt.doc, tt.contentType)
+			if err == nil {
+				t.Fatalf("expected an error, got %d items", len(items))
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to mention %q", err, tt.wantErr)
+			}
+			if class, transient := classifyFetchError(err); class != errorClassParse || transient {
+				t.Errorf("classified as a %s error (transient: %v), want a permanent parse error", class, transient)
+			}
+		})
+	}
+}
@@ -0,0 +1,497 @@
+package cli
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/voidarchive/Gator/internal/config"
+)
+
+const (
+	defaultConnectTimeout = 10 * time.Second
+	defaultReadTimeout    = 30 * time.Second
+	defaultFetchTimeout   = time.Minute
+	defaultMaxFeedSizeMB  = 10
+	defaultUserAgent      = "gator"
+)
+
+// feedRequest describes a fetch; ETag and LastModified come from the previous
+// response and make the request conditional, and Options are the feed's own
+// settings for fetching it
+type feedRequest struct {
+	URL          string
+	ETag         string
+	LastModified string
+	Options      fetchOptions
+}
+
+// fetchResult is what came back from a fetch. When NotModified is set the
+// server had nothing new and Body and Stream are nil; otherwise whichever of
+// them is set has to be closed. PermanentURL is set when the request was
+// permanently redirected to somewhere else.
+type fetchResult struct {
+	Body         io.ReadCloser
+	ContentType  string
+	ETag         string
+	LastModified string
+	NotModified  bool
+	PermanentURL string
+	Stream       *feedStream
+}
+
+// httpStatusError is a response that was neither 200 nor 304
+type httpStatusError struct {
+	StatusCode int
+	RetryAfter string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
+// feedFetcher fetches feeds within the configured timeouts and size limit.
+// The aggregator, which fetches many feeds at once, also has it limit how hard
+// each host is hit.
+type feedFetcher struct {
+	client       *http.Client
+	userAgent    string
+	fetchTimeout time.Duration
+	maxBodySize  int64
+	hosts        *hostLimiter // nil unless limitHosts was called
+}
+
+// feedProxyKey is the context key for a feed's own proxy, which takes the
+// place of the global one for that feed's requests
+type feedProxyKey struct{}
+
+func newFeedFetcher(cfg *config.Config) (*feedFetcher, error) {
+	connectTimeout, err := parseTimeout(cfg.ConnectTimeout, defaultConnectTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid connect_timeout: %v", err)
+	}
+	readTimeout, err := parseTimeout(cfg.ReadTimeout, defaultReadTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid read_timeout: %v", err)
+	}
+	fetchTimeout, err := parseTimeout(cfg.FetchTimeout, defaultFetchTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fetch_timeout: %v", err)
+	}
+	maxFeedSizeMB := cfg.MaxFeedSizeMB
+	if maxFeedSizeMB <= 0 {
+		maxFeedSizeMB = defaultMaxFeedSizeMB
+	}
+
+	dialer := &net.Dialer{Timeout: connectTimeout}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &deadlineConn{Conn: conn, timeout: readTimeout}, nil
+	}
+	transport.TLSHandshakeTimeout = connectTimeout
+	transport.ResponseHeaderTimeout = readTimeout
+
+	defaultProxy := http.ProxyFromEnvironment
+	if cfg.Proxy != "" {
+		proxy, err := parseProxyURL(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy: %v", err)
+		}
+		defaultProxy = http.ProxyURL(proxy)
+	}
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		if proxy, ok := req.Context().Value(feedProxyKey{}).(*url.URL); ok {
+			return proxy, nil
+		}
+		return defaultProxy(req)
+	}
+
+	if cfg.CAFile != "" || cfg.ClientCertFile != "" {
+		transport.TLSClientConfig, err = loadTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	userAgent := cfg.UserAgent
+	if userAgent == "" {
+		userAgent = defaultUserAgent
+	}
+
+	return &feedFetcher{
+		client:       &http.Client{Transport: transport},
+		userAgent:    userAgent,
+		fetchTimeout: fetchTimeout,
+		maxBodySize:  int64(maxFeedSizeMB) << 20,
+	}, nil
+}
+
+// parseProxyURL accepts HTTP(S) and SOCKS5 proxies
+func parseProxyURL(value string) (*url.URL, error) {
+	proxy, err := url.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+	switch proxy.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("%s isn't an http, https or socks5 proxy URL", value)
+	}
+	if proxy.Host == "" {
+		return nil, fmt.Errorf("%s has no host", value)
+	}
+	return proxy, nil
+}
+
+// loadTLSConfig trusts the CA bundle from the config on top of the system's
+// CAs, and presents the client certificate to servers that ask for one
+func loadTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+	if cfg.CAFile != "" {
+		bundle, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading ca_file: %v", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(bundle) {
+			return nil, fmt.Errorf("no certificates found in ca_file %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.ClientCertFile != "" {
+		// The key may be in the same file as the certificate
+		keyFile := cfg.ClientKeyFile
+		if keyFile == "" {
+			keyFile = cfg.ClientCertFile
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCertFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+func parseTimeout(value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("must be positive")
+	}
+	return d, nil
+}
+
+// deadlineConn fails any read that waits longer than timeout for data, which
+// catches servers that stall partway through a response
+type deadlineConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *deadlineConn) Read(b []byte) (int, error) {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(b)
+}
+
+// limitHosts makes the fetcher polite to hosts that serve several feeds
+func (f *feedFetcher) limitHosts(requestsPerMinute, maxInFlight int) {
+	if requestsPerMinute <= 0 {
+		requestsPerMinute = defaultHostRateLimit
+	}
+	if maxInFlight <= 0 {
+		maxInFlight = defaultHostMaxInFlight
+	}
+	f.hosts = newHostLimiter(requestsPerMinute, maxInFlight)
+}
+
+// fetchFeed fetches a feed and starts parsing it. The body is read as the
+// returned stream is, so any host limit stays taken until it's closed.
+func (f *feedFetcher) fetchFeed(ctx context.Context, req feedRequest) (*fetchResult, error) {
+	var host string
+	release := func() {}
+	if f.hosts != nil {
+		u, err := url.Parse(req.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid feed URL: %v", err)
+		}
+		host = strings.ToLower(u.Hostname())
+
+		release, err = f.hosts.acquire(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := f.fetchURL(ctx, req)
+	if err != nil {
+		release()
+		var statusErr *httpStatusError
+		if f.hosts != nil && errors.As(err, &statusErr) &&
+			(statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode == http.StatusServiceUnavailable) {
+			wait, ok := parseRetryAfter(statusErr.RetryAfter, time.Now())
+			if !ok {
+				wait = defaultRetryAfter
+			}
+			until := time.Now().Add(min(wait, maxRetryAfter))
+			f.hosts.deferHost(host, until)
+			return nil, &hostDeferredError{Host: host, Until: until, Err: err}
+		}
+		return nil, err
+	}
+	if result.NotModified {
+		release()
+		return result, nil
+	}
+
+	body := &releasingBody{ReadCloser: result.Body, release: release}
+	result.Body = nil
+	result.Stream, err = newFeedStream(body, result.ContentType, req.URL)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// releasingBody gives back a host limiter slot once the body is closed
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+// fetchURL GETs a URL with the feed's own headers, credentials and proxy,
+// conditionally when the request carries validators from an earlier response
+func (f *feedFetcher) fetchURL(ctx context.Context, feedReq feedRequest) (*fetchResult, error) {
+	opts := feedReq.Options
+	ctx, cancel := context.WithCancel(ctx)
+	budget := &transferBudget{remaining: f.fetchTimeout, total: f.fetchTimeout, cancel: cancel}
+	req, err := f.newRequest(ctx, feedReq.URL, opts)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if feedReq.ETag != "" {
+		req.Header.Set("If-None-Match", feedReq.ETag)
+	}
+	if feedReq.LastModified != "" {
+		req.Header.Set("If-Modified-Since", feedReq.LastModified)
+	}
+
+	// Follow redirects as usual, but remember where the 301s/308s at the start
+	// of the chain lead: that's as far as the feed has moved permanently, and
+	// any hops after the first temporary redirect don't count
+	var permanentURL string
+	permanent := true
+	client := *f.client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		dropHeadersOffHost(req, via, opts)
+		status := req.Response.StatusCode
+		if permanent && (status == http.StatusMovedPermanently || status == http.StatusPermanentRedirect) {
+			permanentURL = req.URL.String()
+		} else {
+			permanent = false
+		}
+		return nil
+	}
+	stop := budget.start()
+	resp, err := client.Do(req)
+	stop()
+	if err != nil {
+		cancel()
+		if budget.expired.Load() {
+			return nil, budget.timeoutError()
+		}
+		return nil, newRequestError(err, fmt.Errorf("error making request: %v", err))
+	}
+	// From here on the body's Close cancels the request
+	resp.Body = &cancelingBody{ReadCloser: resp.Body, cancel: cancel}
+
+	result := &fetchResult{
+		ContentType:  resp.Header.Get("Content-Type"),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		PermanentURL: permanentURL,
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		// A 304 may leave the validators out, in which case the old ones still apply
+		if result.ETag == "" {
+			result.ETag = feedReq.ETag
+		}
+		if result.LastModified == "" {
+			result.LastModified = feedReq.LastModified
+		}
+		result.NotModified = true
+		return result, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, &httpStatusError{StatusCode: resp.StatusCode, RetryAfter: resp.Header.Get("Retry-After")}
+	}
+
+	if resp.ContentLength > f.maxBodySize {
+		resp.Body.Close()
+		return nil, f.tooLargeError()
+	}
+	result.Body = &limitedBody{body: resp.Body, max: f.maxBodySize, tooLarge: f.tooLargeError(), budget: budget}
+	return result, nil
+}
+
+// newRequest makes a GET request with the configured User-Agent and the
+// feed's own headers, credentials and proxy
+func (f *feedFetcher) newRequest(ctx context.Context, rawURL string, opts fetchOptions) (*http.Request, error) {
+	if opts.Proxy != "" {
+		proxy, err := parseProxyURL(opts.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy: %v", err)
+		}
+		ctx = context.WithValue(ctx, feedProxyKey{}, proxy)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+	req.Header.Set("User-Agent", f.userAgent)
+	for name, values := range opts.Headers {
+		req.Header[name] = values
+	}
+	opts.Auth.apply(req)
+	return req, nil
+}
+
+// dropHeadersOffHost takes the feed's own headers off a redirect to another
+// host, since they may hold secrets meant for the feed's host alone. Go only
+// drops Authorization and Cookie by itself.
+func dropHeadersOffHost(req *http.Request, via []*http.Request, opts fetchOptions) {
+	if req.URL.Host == via[0].URL.Host {
+		return
+	}
+	for name := range opts.Headers {
+		req.Header.Del(name)
+	}
+}
+
+// transferBudget is how much of fetch_timeout is left. It only runs down
+// while waiting on the server, so the time spent saving posts as the body
+// streams in doesn't count against it.
+type transferBudget struct {
+	remaining time.Duration
+	total     time.Duration
+	cancel    context.CancelFunc
+	expired   atomic.Bool
+}
+
+// start cancels the request if it's still waiting on the server once the
+// budget runs out; the returned stop takes the time waited off the budget
+func (b *transferBudget) start() (stop func()) {
+	began := time.Now()
+	timer := time.AfterFunc(b.remaining, func() {
+		b.expired.Store(true)
+		b.cancel()
+	})
+	return func() {
+		timer.Stop()
+		b.remaining -= time.Since(began)
+	}
+}
+
+func (b *transferBudget) timeoutError() error {
+	return &fetchError{Class: errorClassTimeout, Err: fmt.Errorf("timed out: fetch took longer than %v", b.total)}
+}
+
+type cancelingBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// limitedBody fails reads once a response body goes past the size limit or
+// the transfer budget, and classifies errors reading it the same way as
+// errors making the request
+type limitedBody struct {
+	body     io.ReadCloser
+	max      int64
+	read     int64
+	tooLarge error
+	budget   *transferBudget
+	err      error
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	// Read one byte past the limit to tell a feed that's exactly at it from
+	// one that's over
+	if remaining := b.max - b.read + 1; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	stop := b.budget.start()
+	n, err := b.body.Read(p)
+	stop()
+	b.read += int64(n)
+	if b.read > b.max {
+		b.err = b.tooLarge
+		return 0, b.err
+	}
+	if err != nil && err != io.EOF && b.budget.expired.Load() {
+		b.err = b.budget.timeoutError()
+		return n, b.err
+	}
+	if err != nil && err != io.EOF {
+		b.err = newRequestError(err, fmt.Errorf("error reading response body: %v", err))
+		return n, b.err
+	}
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.body.Close()
+}
+
+func (f *feedFetcher) tooLargeError() error {
+	return &fetchError{
+		Class:     errorClassSize,
+		Permanent: true,
+		Err:       fmt.Errorf("feed too large: over %s", formatBytes(f.maxBodySize)),
+	}
+}
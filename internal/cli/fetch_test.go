@@ -0,0 +1,282 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/voidarchive/Gator/internal/config"
+)
+
+func newTestFetcher(t *testing.T, cfg config.Config) *feedFetcher {
+	t.Helper()
+	fetcher, err := newFeedFetcher(&cfg)
+	if err != nil {
+		t.Fatalf("newFeedFetcher: %v", err)
+	}
+	return fetcher
+}
+
+const testRSS = `<rss version="2.0"><channel><title>T</title>` +
+	`<item><guid>1</guid><title>One</title></item><item><guid>2</guid><title>Two</title></item></channel></rss>`
+
+func TestFetchURL(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			// Leaves the validators out, as some servers do
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Wed, 01 May 2024 10:00:00 GMT")
+		fmt.Fprint(w, testRSS)
+	})
+	mux.HandleFunc("/headers", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s|%s|%s", r.UserAgent(), r.Header.Get("X-Api-Key"), r.Header.Get("Authorization"))
+	})
+	mux.Handle("/moved", http.RedirectHandler("/moved-again", http.StatusMovedPermanently))
+	mux.Handle("/moved-again", http.RedirectHandler("/temporary", http.StatusPermanentRedirect))
+	mux.Handle("/temporary", http.RedirectHandler("/moved-after-temporary", http.StatusFound))
+	mux.Handle("/moved-after-temporary", http.RedirectHandler("/feed", http.StatusMovedPermanently))
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusGone)
+	})
+	mux.HandleFunc("/down", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusBadGateway)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	fetcher := newTestFetcher(t, config.Config{UserAgent: "gator-test"})
+	ctx := context.Background()
+
+	t.Run("fetch", func(t *testing.T) {
+		result, err := fetcher.fetchFeed(ctx, feedRequest{URL: srv.URL + "/feed"})
+		if err != nil {
+			t.Fatalf("fetchFeed: %v", err)
+		}
+		defer result.Stream.Close()
+		if result.ETag != `"v1"` || result.LastModified != "Wed, 01 May 2024 10:00:00 GMT" {
+			t.Errorf("validators = %q, %q", result.ETag, result.LastModified)
+		}
+		if result.NotModified || result.PermanentURL != "" {
+			t.Errorf("NotModified = %v, PermanentURL = %q", result.NotModified, result.PermanentURL)
+		}
+		if title := result.Stream.Feed().Title; title != "T" {
+			t.Errorf("title = %q, want T", title)
+		}
+	})
+
+	t.Run("not modified", func(t *testing.T) {
+		result, err := fetcher.fetchFeed(ctx, feedRequest{URL: srv.URL + "/feed", ETag: `"v1"`, LastModified: "yesterday"})
+		if err != nil {
+			t.Fatalf("fetchFeed: %v", err)
+		}
+		if !result.NotModified || result.Stream != nil {
+			t.Fatalf("NotModified = %v, Stream = %v", result.NotModified, result.Stream)
+		}
+		if result.ETag != `"v1"` || result.LastModified != "yesterday" {
+			t.Errorf("validators = %q, %q, want the ones sent", result.ETag, result.LastModified)
+		}
+	})
+
+	t.Run("headers", func(t *testing.T) {
+		opts := fetchOptions{
+			Headers: http.Header{"X-Api-Key": {"secret"}},
+			Auth:    feedAuth{Type: "bearer", Token: "token"},
+		}
+		result, err := fetcher.fetchURL(ctx, feedRequest{URL: srv.URL + "/headers", Options: opts})
+		if err != nil {
+			t.Fatalf("fetchURL: %v", err)
+		}
+		defer result.Body.Close()
+		body := readBody(t, result)
+		if body != "gator-test|secret|Bearer token" {
+			t.Errorf("server saw %q", body)
+		}
+	})
+
+	t.Run("redirects", func(t *testing.T) {
+		tests := []struct {
+			path, wantPermanent string
+		}{
+			{"/moved", srv.URL + "/temporary"},
+			{"/moved-again", srv.URL + "/temporary"},
+			{"/temporary", ""},
+			{"/moved-after-temporary", srv.URL + "/feed"},
+		}
+		for _, tt := range tests {
+			result, err := fetcher.fetchFeed(ctx, feedRequest{URL: srv.URL + tt.path})
+			if err != nil {
+				t.Fatalf("fetchFeed %s: %v", tt.path, err)
+			}
+			result.Stream.Close()
+			if result.PermanentURL != tt.wantPermanent {
+				t.Errorf("%s: PermanentURL = %q, want %q", tt.path, result.PermanentURL, tt.wantPermanent)
+			}
+		}
+	})
+
+	t.Run("status", func(t *testing.T) {
+		tests := []struct {
+			path          string
+			wantClass     errorClass
+			wantTransient bool
+		}{
+			{"/gone", errorClassClient, false},
+			{"/missing", errorClassClient, false},
+			{"/down", errorClassServer, true},
+		}
+		for _, tt := range tests {
+			_, err := fetcher.fetchFeed(ctx, feedRequest{URL: srv.URL + tt.path})
+			var statusErr *httpStatusError
+			if !errors.As(err, &statusErr) {
+				t.Fatalf("%s: error = %v, want a status error", tt.path, err)
+			}
+			if class, transient := classifyFetchError(err); class != tt.wantClass || transient != tt.wantTransient {
+				t.Errorf("%s: classified as %s (transient: %v), want %s (transient: %v)",
+					tt.path, class, transient, tt.wantClass, tt.wantTransient)
+			}
+		}
+	})
+}
+
+func TestFetchURLDropsHeadersOffHost(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s|%s|%s", r.UserAgent(), r.Header.Get("X-Api-Key"), r.Header.Get("Authorization"))
+	}))
+	defer other.Close()
+	// Another name for the same machine, since Go itself only keeps
+	// Authorization from going to other host names
+	elsewhere := strings.Replace(other.URL, "127.0.0.1", "localhost", 1) + "/elsewhere"
+	srv := httptest.NewServer(http.RedirectHandler(elsewhere, http.StatusFound))
+	defer srv.Close()
+
+	fetcher := newTestFetcher(t, config.Config{UserAgent: "gator-test"})
+	opts := fetchOptions{
+		Headers: http.Header{"X-Api-Key": {"secret"}},
+		Auth:    feedAuth{Type: "basic", Username: "user", Password: "pass"},
+	}
+	result, err := fetcher.fetchURL(context.Background(), feedRequest{URL: srv.URL, Options: opts})
+	if err != nil {
+		t.Fatalf("fetchURL: %v", err)
+	}
+	defer result.Body.Close()
+	if body := readBody(t, result); body != "gator-test||" {
+		t.Errorf("other host saw %q, want only the User-Agent", body)
+	}
+}
+
+// TestFetchFeedSizeLimit checks that a feed going past max_feed_size_mb
+// keeps the items before the limit, and is only rejected when there are none
+func TestFetchFeedSizeLimit(t *testing.T) {
+	const limit = 1 << 20
+	item := "<item><guid>%d</guid><title>Item %d</title><description>" + strings.Repeat("x", 200) + "</description></item>"
+
+	mux := http.NewServeMux()
+	// Streamed without a Content-Length, so the limit is only found while reading
+	mux.HandleFunc("/long", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<rss version="2.0"><channel><title>Archive</title>`)
+		for i := 0; i*len(item) < 2*limit; i++ {
+			fmt.Fprintf(w, item, i, i)
+		}
+		fmt.Fprint(w, "</channel></rss>")
+	})
+	mux.HandleFunc("/one-huge-item", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<rss version="2.0"><channel><title>Huge</title><item><title>`)
+		w.(http.Flusher).Flush()
+		fmt.Fprint(w, strings.Repeat("x", 2*limit))
+		fmt.Fprint(w, "</title></item></channel></rss>")
+	})
+	mux.HandleFunc("/declared", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", fmt.Sprint(2*limit))
+		fmt.Fprint(w, strings.Repeat(" ", 2*limit))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	fetcher := newTestFetcher(t, config.Config{MaxFeedSizeMB: 1})
+	ctx := context.Background()
+
+	t.Run("items before the limit", func(t *testing.T) {
+		result, err := fetcher.fetchFeed(ctx, feedRequest{URL: srv.URL + "/long"})
+		if err != nil {
+			t.Fatalf("fetchFeed: %v", err)
+		}
+		defer result.Stream.Close()
+		items := 0
+		for {
+			_, err := result.Stream.Next()
+			if err != nil {
+				if err != io.EOF {
+					t.Fatalf("Next: %v", err)
+				}
+				break
+			}
+			items++
+		}
+		if max := limit / len(item); items == 0 || items > max {
+			t.Errorf("got %d items, want up to the %d that fit", items, max)
+		}
+		repairs := result.Stream.Feed().Repairs
+		want := fmt.Sprintf("kept %d items before feed too large: over 1.0 MB", items)
+		if len(repairs) != 1 || repairs[0] != want {
+			t.Errorf("repairs = %q, want %q", repairs, want)
+		}
+	})
+
+	for _, path := range []string{"/one-huge-item", "/declared"} {
+		t.Run(path, func(t *testing.T) {
+			result, err := fetcher.fetchFeed(ctx, feedRequest{URL: srv.URL + path})
+			if err == nil {
+				result.Stream.Close()
+				t.Fatal("expected the feed to be rejected")
+			}
+			if class, transient := classifyFetchError(err); class != errorClassSize || transient {
+				t.Errorf("error %v classified as %s (transient: %v), want a permanent size error", err, class, transient)
+			}
+		})
+	}
+}
+
+func TestFetchTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<rss version="2.0"><channel><title>Slow</title>`)
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	fetcher := newTestFetcher(t, config.Config{FetchTimeout: "200ms"})
+	started := time.Now()
+	_, err := fetcher.fetchFeed(context.Background(), feedRequest{URL: srv.URL})
+	if err == nil {
+		t.Fatal("expected the fetch to time out")
+	}
+	if class, transient := classifyFetchError(err); class != errorClassTimeout || !transient {
+		t.Errorf("error %v classified as %s (transient: %v), want a transient timeout", err, class, transient)
+	}
+	if elapsed := time.Since(started); elapsed > 2*time.Second {
+		t.Errorf("took %v to time out", elapsed)
+	}
+}
+
+func readBody(t *testing.T, result *fetchResult) string {
+	t.Helper()
+	var body strings.Builder
+	if _, err := io.Copy(&body, result.Body); err != nil {
+		t.Fatalf("reading body: %v", err)
+	}
+	return body.String()
+}
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+)
+
+type errorClass string
+
+const (
+	errorClassDNS     errorClass = "dns"
+	errorClassTimeout errorClass = "timeout"
+	errorClassTLS     errorClass = "tls"
+	errorClassClient  errorClass = "4xx"
+	errorClassServer  errorClass = "5xx"
+	errorClassParse   errorClass = "parse"
+	errorClassSize    errorClass = "size"
+	errorClassNetwork errorClass = "network"
+)
+
+const (
+	// Transient failures are retried after baseRetryDelay, doubling with every
+	// consecutive failure up to maxRetryDelay
+	baseRetryDelay = time.Minute
+	maxRetryDelay  = 6 * time.Hour
+
+	// Permanent failures won't fix themselves soon, so they're only retried daily
+	permanentRetryDelay = 24 * time.Hour
+)
+
+// fetchError is a failed fetch along with what kind of failure it was;
+// Permanent is set when retrying soon won't help
+type fetchError struct {
+	Class     errorClass
+	Permanent bool
+	Err       error
+}
+
+func (e *fetchError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *fetchError) Unwrap() error {
+	return e.Err
+}
+
+// newRequestError classifies an error from making a request or reading its
+// response, keeping msg as the message
+func newRequestError(err error, msg error) *fetchError {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var certErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var unknownAuthErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+
+	switch {
+	case errors.As(err, &dnsErr):
+		if dnsErr.IsTimeout {
+			return &fetchError{Class: errorClassTimeout, Err: fmt.Errorf("timed out: %v", msg)}
+		}
+		// A name that doesn't exist is most likely a typo or a dead domain
+		return &fetchError{Class: errorClassDNS, Permanent: dnsErr.IsNotFound, Err: msg}
+	case errors.As(err, &certErr), errors.As(err, &recordErr), errors.As(err, &unknownAuthErr),
+		errors.As(err, &hostnameErr), errors.As(err, &invalidErr):
+		return &fetchError{Class: errorClassTLS, Permanent: true, Err: msg}
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return &fetchError{Class: errorClassTimeout, Err: fmt.Errorf("timed out: %v", msg)}
+	}
+	return &fetchError{Class: errorClassNetwork, Err: msg}
+}
+
+// classifyFetchError works out what kind of failure err is and whether it's
+// worth retrying soon
+func classifyFetchError(err error) (errorClass, bool) {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		switch {
+		case statusErr.StatusCode >= 500:
+			return errorClassServer, true
+		case statusErr.StatusCode == http.StatusRequestTimeout || statusErr.StatusCode == http.StatusTooManyRequests:
+			return errorClassClient, true
+		default:
+			return errorClassClient, false
+		}
+	}
+	var fetchErr *fetchError
+	if errors.As(err, &fetchErr) {
+		return fetchErr.Class, !fetchErr.Permanent
+	}
+	return errorClassNetwork, true
+}
+
+// retryDelay is how long to wait before fetching a feed again after its
+// failures'th consecutive failure. Transient failures back off exponentially
+// with jitter so that feeds which failed together don't retry together.
+func retryDelay(failures int, transient bool) time.Duration {
+	if !transient {
+		return permanentRetryDelay
+	}
+	delay := maxRetryDelay
+	if failures < 20 {
+		delay = min(baseRetryDelay<<(failures-1), maxRetryDelay)
+	}
+	// Somewhere between half and all of the full delay
+	return delay/2 + rand.N(delay/2+1)
+}
@@ -0,0 +1,335 @@
+package cli
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/voidarchive/Gator/internal/database"
+)
+
+const fetchOptionsUsage = `[--header "Name: value"]... [--basic user:password] [--bearer token] [--proxy url] [--no-auth] [--no-proxy]`
+
+// fetchOptions are a feed's own settings for fetching it, applied on top of
+// the global ones from the config
+type fetchOptions struct {
+	Headers http.Header
+	Auth    feedAuth
+	Proxy   string
+}
+
+// feedAuth is how a feed's requests authenticate, if they do at all
+type feedAuth struct {
+	Type     string // "basic" or "bearer"
+	Username string
+	Password string
+	Token    string
+}
+
+func (a feedAuth) apply(req *http.Request) {
+	switch a.Type {
+	case "basic":
+		req.SetBasicAuth(a.Username, a.Password)
+	case "bearer":
+		req.Header.Set("Authorization", "Bearer "+a.Token)
+	}
+}
+
+func (o fetchOptions) isEmpty() bool {
+	return len(o.Headers) == 0 && o.Auth.Type == "" && o.Proxy == ""
+}
+
+// parseFetchFlags applies the fetch option flags in args to opts and returns
+// the arguments that aren't fetch options. A header with an empty value
+// removes it.
+func parseFetchFlags(args []string, opts *fetchOptions) ([]string, error) {
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "--no-auth":
+			opts.Auth = feedAuth{}
+			continue
+		case "--no-proxy":
+			opts.Proxy = ""
+			continue
+		case "--header", "--basic", "--bearer", "--proxy":
+		default:
+			rest = append(rest, arg)
+			continue
+		}
+
+		if i+1 >= len(args) {
+			return nil, fmt.Errorf("%s requires a value", arg)
+		}
+		i++
+		value := args[i]
+		switch arg {
+		case "--header":
+			name, headerValue, ok := strings.Cut(value, ":")
+			name = strings.TrimSpace(name)
+			if !ok || name == "" {
+				return nil, fmt.Errorf("headers look like \"Name: value\"")
+			}
+			if opts.Headers == nil {
+				opts.Headers = make(http.Header)
+			}
+			if headerValue = strings.TrimSpace(headerValue); headerValue == "" {
+				opts.Headers.Del(name)
+			} else {
+				opts.Headers.Set(name, headerValue)
+			}
+		case "--basic":
+			username, password, ok := strings.Cut(value, ":")
+			if !ok || username == "" {
+				return nil, fmt.Errorf("--basic takes user:password")
+			}
+			opts.Auth = feedAuth{Type: "basic", Username: username, Password: password}
+		case "--bearer":
+			opts.Auth = feedAuth{Type: "bearer", Token: value}
+		case "--proxy":
+			if _, err := parseProxyURL(value); err != nil {
+				return nil, fmt.Errorf("invalid proxy: %v", err)
+			}
+			opts.Proxy = value
+		}
+	}
+	return rest, nil
+}
+
+// loadFetchOptions reads a feed's fetch settings, decrypting them. It never
+// creates secret_key, since agg workers run it at the same time and a new key
+// couldn't decrypt settings stored with the old one anyway.
+func loadFetchOptions(ctx context.Context, s *State, feedID uuid.UUID) (fetchOptions, error) {
+	settings, err := s.DB.GetFeedFetchSettings(ctx, feedID)
+	if err == sql.ErrNoRows {
+		return fetchOptions{}, nil
+	}
+	if err != nil {
+		return fetchOptions{}, fmt.Errorf("error getting fetch settings: %v", err)
+	}
+	key, err := s.Cfg.CredentialsKey()
+	if err != nil {
+		return fetchOptions{}, err
+	}
+	open := func(sealed []byte) (string, error) {
+		if sealed == nil {
+			return "", nil
+		}
+		plaintext, err := openSecret(key, sealed)
+		if err != nil {
+			return "", fmt.Errorf("error decrypting fetch settings (has secret_key changed?): %v", err)
+		}
+		return string(plaintext), nil
+	}
+
+	var opts fetchOptions
+	if opts.Proxy, err = open(settings.Proxy); err != nil {
+		return fetchOptions{}, err
+	}
+	headers, err := open(settings.Headers)
+	if err != nil {
+		return fetchOptions{}, err
+	}
+	if headers != "" {
+		if err := json.Unmarshal([]byte(headers), &opts.Headers); err != nil {
+			return fetchOptions{}, fmt.Errorf("error reading stored headers: %v", err)
+		}
+	}
+	if settings.AuthType.Valid {
+		credentials, err := open(settings.AuthCredentials)
+		if err != nil {
+			return fetchOptions{}, err
+		}
+		opts.Auth.Type = settings.AuthType.String
+		if opts.Auth.Type == "basic" {
+			opts.Auth.Username, opts.Auth.Password, _ = strings.Cut(credentials, ":")
+		} else {
+			opts.Auth.Token = credentials
+		}
+	}
+	return opts, nil
+}
+
+// saveFetchOptions stores a feed's fetch settings, or removes them when
+// there's nothing left to store. Headers, credentials and the proxy are all
+// encrypted, since any of them may hold a secret such as an API key.
+func saveFetchOptions(ctx context.Context, s *State, feedID uuid.UUID, opts fetchOptions) error {
+	if opts.isEmpty() {
+		if err := s.DB.DeleteFeedFetchSettings(ctx, feedID); err != nil {
+			return fmt.Errorf("error clearing fetch settings: %v", err)
+		}
+		return nil
+	}
+	key, err := s.Cfg.EnsureCredentialsKey()
+	if err != nil {
+		return fmt.Errorf("error getting credentials key: %v", err)
+	}
+	seal := func(plaintext string) ([]byte, error) {
+		if plaintext == "" {
+			return nil, nil
+		}
+		sealed, err := sealSecret(key, []byte(plaintext))
+		if err != nil {
+			return nil, fmt.Errorf("error encrypting fetch settings: %v", err)
+		}
+		return sealed, nil
+	}
+
+	params := database.SetFeedFetchSettingsParams{
+		FeedID:    feedID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		AuthType:  sql.NullString{String: opts.Auth.Type, Valid: opts.Auth.Type != ""},
+	}
+	if len(opts.Headers) > 0 {
+		data, err := json.Marshal(opts.Headers)
+		if err != nil {
+			return fmt.Errorf("error encoding headers: %v", err)
+		}
+		if params.Headers, err = seal(string(data)); err != nil {
+			return err
+		}
+	}
+	if opts.Auth.Type != "" {
+		secret := opts.Auth.Token
+		if opts.Auth.Type == "basic" {
+			secret = opts.Auth.Username + ":" + opts.Auth.Password
+		}
+		if params.AuthCredentials, err = seal(secret); err != nil {
+			return err
+		}
+	}
+	if params.Proxy, err = seal(opts.Proxy); err != nil {
+		return err
+	}
+
+	if err := s.DB.SetFeedFetchSettings(ctx, params); err != nil {
+		return fmt.Errorf("error saving fetch settings: %v", err)
+	}
+	return nil
+}
+
+// sealSecret encrypts with AES-256-GCM, prefixing the result with its nonce
+func sealSecret(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func openSecret(key, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("stored credentials are corrupt")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// HandlerFetchOptions shows or changes how a feed is fetched: extra headers,
+// credentials and a proxy of its own
+func HandlerFetchOptions(s *State, cmd Command) error {
+	if len(cmd.Args) < 1 {
+		return fmt.Errorf("usage: fetchopts <feed-url> [--clear] %s", fetchOptionsUsage)
+	}
+	ctx := context.Background()
+
+	feed, err := s.DB.GetFeedByUrl(ctx, cmd.Args[0])
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("feed not found")
+		}
+		return fmt.Errorf("error getting feed: %v", err)
+	}
+	opts, err := loadFetchOptions(ctx, s, feed.ID)
+	if err != nil {
+		return err
+	}
+
+	flags := cmd.Args[1:]
+	if len(flags) > 0 && flags[0] == "--clear" {
+		opts = fetchOptions{}
+		flags = flags[1:]
+	}
+	rest, err := parseFetchFlags(flags, &opts)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("usage: fetchopts <feed-url> [--clear] %s", fetchOptionsUsage)
+	}
+	if len(cmd.Args) > 1 {
+		if err := saveFetchOptions(ctx, s, feed.ID, opts); err != nil {
+			return err
+		}
+		fmt.Printf("Fetch settings for %s updated\n", feed.Name)
+	}
+
+	printFetchOptions(feed, opts)
+	return nil
+}
+
+// printFetchOptions shows a feed's fetch settings without giving away any
+// secrets: header values and proxy credentials are hidden along with the auth
+func printFetchOptions(feed database.Feed, opts fetchOptions) {
+	fmt.Printf("Fetch settings for %s (%s):\n", feed.Name, feed.Url)
+	if opts.isEmpty() {
+		fmt.Println("  None, the global settings from the config apply")
+		return
+	}
+
+	names := make([]string, 0, len(opts.Headers))
+	for name := range opts.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("  Header: %s: (hidden)\n", name)
+	}
+	switch opts.Auth.Type {
+	case "basic":
+		fmt.Printf("  Auth: basic, as %s\n", opts.Auth.Username)
+	case "bearer":
+		fmt.Println("  Auth: bearer token")
+	}
+	if opts.Proxy != "" {
+		fmt.Printf("  Proxy: %s\n", redactProxy(opts.Proxy))
+	}
+}
+
+// redactProxy hides the username and password a proxy URL may carry
+func redactProxy(rawURL string) string {
+	proxy, err := url.Parse(rawURL)
+	if err != nil || proxy.User == nil {
+		return rawURL
+	}
+	proxy.User = url.User("xxxxx")
+	return proxy.String()
+}
@@ -3,7 +3,10 @@ package cli
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"strconv"
 	"strings"
 	"time"
@@ -95,88 +98,237 @@ func HandlerUsers(s *State, cmd Command) error {
 	return nil
 }
 
-func HandlerAgg(s *State, cmd Command) error {
-	if len(cmd.Args) < 1 {
-		return fmt.Errorf("usage: agg <time_between_reqs>")
-	}
+// scrapeFeed fetches one feed that has already been claimed for fetching
+// and saves whatever is new in it
+func scrapeFeed(ctx context.Context, s *State, fetcher *feedFetcher, feed database.Feed) error {
+	fmt.Printf("Fetching feed: %s (%s)\n", feed.Name, feed.Url)
 
-	timeBetweenReqs, err := time.ParseDuration(cmd.Args[0])
+	opts, err := loadFetchOptions(ctx, s, feed.ID)
 	if err != nil {
-		return fmt.Errorf("invalid duration: %v", err)
+		return err
 	}
 
-	fmt.Printf("Collecting feeds every %v\n", timeBetweenReqs)
-
-	ticker := time.NewTicker(timeBetweenReqs)
-	defer ticker.Stop()
-
-	// Run immediately first, then wait for ticker
-	for ; ; <-ticker.C {
-		err := scrapeFeeds(s)
+	// Fetch and parse the feed, whatever its format. The validators from the
+	// last response let the server answer 304 when nothing changed.
+	result, err := fetcher.fetchFeed(ctx, feedRequest{
+		URL:          feed.Url,
+		ETag:         feed.Etag.String,
+		LastModified: feed.LastModified.String,
+		Options:      opts,
+	})
+	var busy *hostBusyError
+	if errors.As(err, &busy) {
+		// Rather than hold up a worker, come back to the feed on its turn
+		deferErr := s.DB.SetFeedNextFetch(ctx, database.SetFeedNextFetchParams{
+			DelaySecs: busy.Wait.Seconds(),
+			ID:        feed.ID,
+		})
+		if deferErr != nil {
+			return fmt.Errorf("error deferring feed: %v", deferErr)
+		}
+		fmt.Printf("%s is busy, fetching %s in %v\n\n", busy.Host, feed.Name, busy.Wait.Round(time.Second))
+		return nil
+	}
+	var deferred *hostDeferredError
+	if errors.As(err, &deferred) {
+		// Leave the feed alone until its host is willing to talk to us again
+		deferErr := s.DB.SetFeedNextFetch(ctx, database.SetFeedNextFetchParams{
+			DelaySecs: max(time.Until(deferred.Until), 0).Seconds(),
+			ID:        feed.ID,
+		})
+		if deferErr != nil {
+			return fmt.Errorf("error deferring feed: %v", deferErr)
+		}
+		return fmt.Errorf("error fetching feed %s: %v", feed.Url, err)
+	}
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusGone {
+		if err := s.DB.RetireFeed(ctx, feed.ID); err != nil {
+			return fmt.Errorf("error retiring feed: %v", err)
+		}
+		return fmt.Errorf("feed %s is gone (410), retired it", feed.Url)
+	}
+	if err != nil {
+		return recordFeedFailure(ctx, s, feed, err)
+	}
+	if !result.NotModified {
+		err := saveFeed(ctx, s, feed, result.Stream)
+		result.Stream.Close()
+		// The feed is read while it's saved, so fetching can still fail here
+		var fetchErr *fetchError
+		if errors.As(err, &fetchErr) {
+			return recordFeedFailure(ctx, s, feed, err)
+		}
 		if err != nil {
-			fmt.Printf("Error scraping feeds: %v\n", err)
-			// Continue the loop even if there's an error
+			return err
 		}
 	}
-}
 
-func parseRSSTime(timeStr string) (time.Time, error) {
-	// Common RSS time formats
-	formats := []string{
-		time.RFC1123Z,               // "Mon, 02 Jan 2006 15:04:05 -0700"
-		time.RFC1123,                // "Mon, 02 Jan 2006 15:04:05 MST"
-		time.RFC822Z,                // "02 Jan 06 15:04 -0700"
-		time.RFC822,                 // "02 Jan 06 15:04 MST"
-		"2006-01-02T15:04:05Z07:00", // ISO 8601
-		"2006-01-02 15:04:05",       // Simple format
+	if err := s.DB.RecordFeedSuccess(ctx, feed.ID); err != nil {
+		return fmt.Errorf("error recording feed success: %v", err)
+	}
+	if result.PermanentURL != "" && result.PermanentURL != feed.Url {
+		moveFeed(ctx, s, feed, result.PermanentURL)
+	}
+	if result.NotModified {
+		fmt.Printf("Feed %s not modified since last fetch\n\n", feed.Name)
+		return nil
 	}
 
-	for _, format := range formats {
-		if t, err := time.Parse(format, timeStr); err == nil {
-			return t, nil
-		}
+	err = s.DB.SetFeedCacheHeaders(ctx, database.SetFeedCacheHeadersParams{
+		ID:           feed.ID,
+		Etag:         sql.NullString{String: result.ETag, Valid: result.ETag != ""},
+		LastModified: sql.NullString{String: result.LastModified, Valid: result.LastModified != ""},
+	})
+	if err != nil {
+		return fmt.Errorf("error saving cache headers: %v", err)
 	}
-	return time.Time{}, fmt.Errorf("unable to parse time: %s", timeStr)
+	return nil
 }
 
-func scrapeFeeds(s *State) error {
-	ctx := context.Background()
+// moveFeed points a permanently redirected feed at its new URL. The old one
+// is kept in the feed's URL history so it can still be used to refer to it.
+func moveFeed(ctx context.Context, s *State, feed database.Feed, newURL string) {
+	other, err := s.DB.GetFeedByUrl(ctx, newURL)
+	if err != nil && err != sql.ErrNoRows {
+		fmt.Printf("Error checking new URL of %s: %v\n", feed.Name, err)
+		return
+	}
+	if err == nil && other.ID != feed.ID {
+		fmt.Printf("Feed %s moved to %s, which is already added as feed %s\n", feed.Name, newURL, other.Name)
+		return
+	}
 
-	// Get the next feed to fetch
-	feed, err := s.DB.GetNextFeedToFetch(ctx)
+	// The history and the new URL are written together, so a move that fails
+	// leaves neither behind
+	err = func() error {
+		tx, err := s.Conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		qtx := s.DB.WithTx(tx)
+
+		err = qtx.AddFeedUrlHistory(ctx, database.AddFeedUrlHistoryParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			FeedID:    feed.ID,
+			Url:       feed.Url,
+		})
+		if err != nil {
+			return err
+		}
+		if err := qtx.UpdateFeedUrl(ctx, database.UpdateFeedUrlParams{ID: feed.ID, Url: newURL}); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}()
 	if err != nil {
-		return fmt.Errorf("error getting next feed to fetch: %v", err)
+		if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "unique") {
+			fmt.Printf("Feed %s moved to %s, which is already added as another feed\n", feed.Name, newURL)
+			return
+		}
+		fmt.Printf("Error updating URL of %s: %v\n", feed.Name, err)
+		return
 	}
+	fmt.Printf("Feed %s moved permanently to %s\n", feed.Name, newURL)
+}
 
-	fmt.Printf("Fetching feed: %s (%s)\n", feed.Name, feed.Url)
-
-	// Mark the feed as fetched before actually fetching to avoid race conditions
-	err = s.DB.MarkFeedFetched(ctx, feed.ID)
+// recordFeedFailure schedules the next attempt at a feed that failed to
+// fetch, backing off further each time transient failures repeat. Feeds that
+// keep failing are disabled until someone re-enables them.
+func recordFeedFailure(ctx context.Context, s *State, feed database.Feed, fetchErr error) error {
+	class, transient := classifyFetchError(fetchErr)
+	failures := feed.ConsecutiveFailures + 1
+	delay := retryDelay(int(failures), transient)
+	disabled := int(failures) >= s.Cfg.FailureThreshold()
+
+	err := s.DB.RecordFeedFailure(ctx, database.RecordFeedFailureParams{
+		ConsecutiveFailures: failures,
+		RetryDelaySecs:      delay.Seconds(),
+		LastError:           sql.NullString{String: fetchErr.Error(), Valid: true},
+		Disabled:            disabled,
+		ID:                  feed.ID,
+	})
 	if err != nil {
-		return fmt.Errorf("error marking feed as fetched: %v", err)
+		return fmt.Errorf("error recording feed failure: %v", err)
 	}
 
-	// Fetch the RSS feed
-	rssFeed, err := fetchFeed(ctx, feed.Url)
-	if err != nil {
-		return fmt.Errorf("error fetching RSS feed %s: %v", feed.Url, err)
+	if disabled {
+		return fmt.Errorf("error fetching feed %s (%s error, disabled after %d failures in a row): %v",
+			feed.Url, class, failures, fetchErr)
 	}
+	kind := "transient"
+	if !transient {
+		kind = "permanent"
+	}
+	return fmt.Errorf("error fetching feed %s (%s %s error, retrying in %v): %v",
+		feed.Url, kind, class, delay.Round(time.Second), fetchErr)
+}
+
+// knownItemsBeforeStop is how many already saved items in a row it takes to
+// stop reading a newest-first feed, since everything after them is older
+// still. A few rather than one, so a pinned or re-dated post doesn't stop it.
+const knownItemsBeforeStop = 5
+
+// saveFeed stores any posts in a freshly fetched feed that haven't been seen
+// before, followed by the feed's metadata. Items are saved as they're read,
+// and reading stops once a newest-first feed gets to posts already saved.
+func saveFeed(ctx context.Context, s *State, feed database.Feed, stream *feedStream) error {
+	// Items without a usable date fall back to when the feed was last built,
+	// or failing that to when we first saw them
+	fallbackTime := time.Now()
+	if lastBuild, ok := stream.Feed().lastBuildTime(); ok {
+		fallbackTime = lastBuild
+	}
+
+	// Only trust the order of a feed whose items all have dates, and read
+	// everything after a failed fetch in case that one stopped partway
+	newestFirst := feed.ConsecutiveFailures == 0
+	var (
+		previous                time.Time
+		processed, saved, known int
+		stoppedEarly            bool
+	)
+	for {
+		item, err := stream.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		processed++
 
-	// Save posts to database
-	fmt.Printf("Found %d posts from %s:\n", len(rssFeed.Channel.Item), rssFeed.Channel.Title)
-	for _, item := range rssFeed.Channel.Item {
-		// Parse the published date - handle different formats
-		var publishedAt sql.NullTime
+		publishedAt := sql.NullTime{Time: fallbackTime, Valid: true}
+		var published time.Time
 		if item.PubDate != "" {
-			// Try common RSS date formats
-			parsedTime, err := parseRSSTime(item.PubDate)
-			if err == nil {
-				publishedAt = sql.NullTime{Time: parsedTime, Valid: true}
+			if parsedTime, err := parseFeedTime(item.PubDate); err == nil {
+				published = parsedTime
+				publishedAt.Time = parsedTime
 			}
 		}
+		if published.IsZero() || (!previous.IsZero() && published.After(previous)) {
+			newestFirst = false
+		}
+		previous = published
+
+		adopted, err := adoptLegacyPost(ctx, s, feed, item)
+		if err != nil {
+			fmt.Printf("Error checking for an earlier copy of post %s: %v\n", item.Title, err)
+			continue
+		}
+		if adopted {
+			known++
+			if newestFirst && known >= knownItemsBeforeStop {
+				stoppedEarly = true
+				break
+			}
+			continue
+		}
 
 		// Create post in database
-		_, err := s.DB.CreatePost(ctx, database.CreatePostParams{
+		post, err := s.DB.CreatePost(ctx, database.CreatePostParams{
 			ID:          uuid.New(),
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
@@ -185,43 +337,235 @@ func scrapeFeeds(s *State) error {
 			Description: sql.NullString{String: item.Description, Valid: item.Description != ""},
 			PublishedAt: publishedAt,
 			FeedID:      feed.ID,
+			Content:     sql.NullString{String: item.Content, Valid: item.Content != ""},
+			Guid:        itemIdentity(item),
 		})
 		if err != nil {
-			// If it's a duplicate URL error, just skip it
+			// Already saved this item for this feed, just skip it
 			if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "unique") {
+				known++
+				if newestFirst && known >= knownItemsBeforeStop {
+					stoppedEarly = true
+					break
+				}
 				continue
 			}
 			// Log other errors but continue processing
 			fmt.Printf("Error saving post %s: %v\n", item.Title, err)
+			continue
+		}
+		saved++
+		known = 0
+
+		for _, enc := range item.Enclosures {
+			_, err := s.DB.CreateEnclosure(ctx, database.CreateEnclosureParams{
+				ID:              uuid.New(),
+				CreatedAt:       time.Now(),
+				UpdatedAt:       time.Now(),
+				PostID:          post.ID,
+				Url:             enc.URL,
+				MimeType:        sql.NullString{String: enc.Type, Valid: enc.Type != ""},
+				Length:          sql.NullInt64{Int64: enc.Length, Valid: enc.Length > 0},
+				DurationSeconds: sql.NullInt32{Int32: int32(enc.Duration), Valid: enc.Duration > 0},
+				Episode:         sql.NullInt32{Int32: int32(enc.Episode), Valid: enc.Episode > 0},
+				Season:          sql.NullInt32{Int32: int32(enc.Season), Valid: enc.Season > 0},
+				ImageUrl:        sql.NullString{String: enc.Image, Valid: enc.Image != ""},
+			})
+			if err != nil && !strings.Contains(err.Error(), "duplicate") {
+				fmt.Printf("Error saving enclosure %s: %v\n", enc.URL, err)
+			}
+		}
+
+		for _, media := range item.Media {
+			err := s.DB.CreateMediaItem(ctx, database.CreateMediaItemParams{
+				ID:           uuid.New(),
+				CreatedAt:    time.Now(),
+				UpdatedAt:    time.Now(),
+				PostID:       post.ID,
+				Url:          media.URL,
+				Medium:       sql.NullString{String: media.Medium, Valid: media.Medium != ""},
+				MimeType:     sql.NullString{String: media.Type, Valid: media.Type != ""},
+				ThumbnailUrl: sql.NullString{String: media.Thumbnail, Valid: media.Thumbnail != ""},
+				Width:        sql.NullInt32{Int32: int32(media.Width), Valid: media.Width > 0},
+				Height:       sql.NullInt32{Int32: int32(media.Height), Valid: media.Height > 0},
+			})
+			if err != nil {
+				fmt.Printf("Error saving media %s: %v\n", media.URL, err)
+			}
+		}
+
+		for _, author := range item.Authors {
+			if author.Name == "" {
+				continue
+			}
+			err := s.DB.CreatePostAuthor(ctx, database.CreatePostAuthorParams{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+				PostID:    post.ID,
+				Name:      author.Name,
+				Email:     sql.NullString{String: author.Email, Valid: author.Email != ""},
+			})
+			if err != nil {
+				fmt.Printf("Error saving author %s: %v\n", author.Name, err)
+			}
+		}
+
+		for _, category := range item.Categories {
+			category = strings.TrimSpace(category)
+			if category == "" {
+				continue
+			}
+			err := s.DB.CreatePostCategory(ctx, database.CreatePostCategoryParams{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+				PostID:    post.ID,
+				Name:      category,
+			})
+			if err != nil {
+				fmt.Printf("Error saving category %s: %v\n", category, err)
+			}
 		}
 	}
-	fmt.Printf("Processed %d posts from %s\n\n", len(rssFeed.Channel.Item), rssFeed.Channel.Title)
 
+	parsedFeed := stream.Feed()
+	if err := updateFeedMetadata(ctx, s, feed, parsedFeed); err != nil {
+		return err
+	}
+	if parsedFeed.Encoding != "utf-8" {
+		fmt.Printf("Transcoded feed from %s\n", parsedFeed.Encoding)
+	}
+	if len(parsedFeed.Repairs) > 0 {
+		fmt.Printf("Feed is malformed, repairs applied: %s\n", strings.Join(parsedFeed.Repairs, "; "))
+	}
+	if stoppedEarly {
+		fmt.Printf("Processed %d posts from %s, %d new; the rest are already saved\n\n", processed, parsedFeed.Title, saved)
+	} else {
+		fmt.Printf("Processed %d posts from %s, %d new\n\n", processed, parsedFeed.Title, saved)
+	}
+	return nil
+}
+
+func updateFeedMetadata(ctx context.Context, s *State, feed database.Feed, parsedFeed *ParsedFeed) error {
+	nullString := func(s string) sql.NullString {
+		return sql.NullString{String: s, Valid: s != ""}
+	}
+	var lastBuildDate sql.NullTime
+	if t, ok := parsedFeed.lastBuildTime(); ok {
+		lastBuildDate = sql.NullTime{Time: t, Valid: true}
+	}
+
+	err := s.DB.UpdateFeedMetadata(ctx, database.UpdateFeedMetadataParams{
+		ID:            feed.ID,
+		SiteUrl:       nullString(parsedFeed.Link),
+		Description:   nullString(parsedFeed.Description),
+		Language:      nullString(parsedFeed.Language),
+		ImageUrl:      nullString(parsedFeed.Image),
+		Generator:     nullString(parsedFeed.Generator),
+		LastBuildDate: lastBuildDate,
+		Encoding:      nullString(parsedFeed.Encoding),
+	})
+	if err != nil {
+		return fmt.Errorf("error updating feed metadata: %v", err)
+	}
 	return nil
 }
 
+// adoptLegacyPost reports whether item was already saved from before posts had
+// guids, when the URL stood in for one, giving that post the item's real guid
+func adoptLegacyPost(ctx context.Context, s *State, feed database.Feed, item FeedItem) (bool, error) {
+	guid := itemIdentity(item)
+	if item.Link == "" || guid == item.Link {
+		return false, nil
+	}
+	_, err := s.DB.AdoptLegacyPost(ctx, database.AdoptLegacyPostParams{
+		Guid:   guid,
+		FeedID: feed.ID,
+		Url:    item.Link,
+	})
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		// The item is saved under its guid already, alongside the old post
+		if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "unique") {
+			return true, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// itemIdentity is what a post is deduplicated on within its feed: the
+// item's GUID when the feed provides one, otherwise its link
+func itemIdentity(item FeedItem) string {
+	if item.GUID != "" {
+		return item.GUID
+	}
+	if item.Link != "" {
+		return item.Link
+	}
+	return item.Title
+}
+
 func HandlerAddFeed(s *State, cmd Command, user database.User) error {
-	if len(cmd.Args) < 2 {
-		return fmt.Errorf("addFeed requres name and url arguments")
+	var opts fetchOptions
+	args, err := parseFetchFlags(cmd.Args, &opts)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("usage: addfeed [name] <url> %s", fetchOptionsUsage)
+	}
+	var name string
+	rawURL := args[0]
+	if len(args) == 2 {
+		name, rawURL = args[0], args[1]
+	}
+	ctx := context.Background()
+
+	// Make sure there's really a feed there before storing it. A homepage is
+	// fine too; the feed it advertises is used instead.
+	fetcher, err := newFeedFetcher(s.Cfg)
+	if err != nil {
+		return err
+	}
+	candidate, err := locateFeed(ctx, fetcher, rawURL, opts)
+	if err != nil {
+		return fmt.Errorf("error adding feed: %v", err)
+	}
+	if candidate.URL != rawURL {
+		fmt.Printf("Using feed %s\n", candidate.URL)
+	}
+	defer candidate.Stream.Close()
+	if name == "" {
+		name = strings.TrimSpace(candidate.Stream.Feed().Title)
+		if name == "" {
+			return fmt.Errorf("feed has no title, usage: addfeed <name> <url>")
+		}
 	}
-	name := cmd.Args[0]
-	url := cmd.Args[1]
 
-	feed, err := s.DB.CreateFeed(context.Background(), database.CreateFeedParams{
+	feed, err := s.DB.CreateFeed(ctx, database.CreateFeedParams{
 		ID:        uuid.New(),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 		Name:      name,
-		Url:       url,
+		Url:       candidate.URL,
 		UserID:    user.ID,
 	})
 
 	if err != nil {
 		return fmt.Errorf("error creating feed: %v", err)
 	}
+	if !opts.isEmpty() {
+		if err := saveFetchOptions(ctx, s, feed.ID, opts); err != nil {
+			return err
+		}
+	}
 
 	// Automatically follow the feed that was just created
-	_, err = s.DB.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+	_, err = s.DB.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
 		ID:        uuid.New(),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -234,6 +578,31 @@ func HandlerAddFeed(s *State, cmd Command, user database.User) error {
 
 	fmt.Printf("Feed added successfully!\n")
 	fmt.Printf("Feed data: ID=%s, Name=%s, URL=%s, UserID=%s, CreatedAt=%s\n", feed.ID, feed.Name, feed.Url, user.ID, feed.CreatedAt.Format(time.RFC3339))
+
+	// Ingest what's in the feed now rather than waiting for the next agg run
+	if err := s.DB.MarkFeedFetched(ctx, feed.ID); err != nil {
+		return fmt.Errorf("error marking feed as fetched: %v", err)
+	}
+	if err := saveFeed(ctx, s, feed, candidate.Stream); err != nil {
+		// The feed stays added; agg retries it like any other failed fetch
+		var fetchErr *fetchError
+		if errors.As(err, &fetchErr) {
+			return recordFeedFailure(ctx, s, feed, err)
+		}
+		return err
+	}
+	if err := s.DB.RecordFeedSuccess(ctx, feed.ID); err != nil {
+		return fmt.Errorf("error recording feed success: %v", err)
+	}
+	// So the first agg run can ask whether the feed has changed since
+	err = s.DB.SetFeedCacheHeaders(ctx, database.SetFeedCacheHeadersParams{
+		ID:           feed.ID,
+		Etag:         sql.NullString{String: candidate.ETag, Valid: candidate.ETag != ""},
+		LastModified: sql.NullString{String: candidate.LastModified, Valid: candidate.LastModified != ""},
+	})
+	if err != nil {
+		return fmt.Errorf("error saving cache headers: %v", err)
+	}
 	return nil
 }
 
@@ -246,6 +615,24 @@ func HandlerListFeeds(s *State, cmd Command) error {
 	for _, feed := range feeds {
 		fmt.Printf("  Name: %s\n", feed.FeedName)
 		fmt.Printf("  URL: %s\n", feed.Url)
+		if feed.SiteUrl.Valid {
+			fmt.Printf("  Site: %s\n", feed.SiteUrl.String)
+		}
+		if feed.Description.Valid {
+			fmt.Printf("  Description: %s\n", truncateText(htmlToText(feed.Description.String), 200))
+		}
+		if feed.Language.Valid {
+			fmt.Printf("  Language: %s\n", feed.Language.String)
+		}
+		if feed.ImageUrl.Valid {
+			fmt.Printf("  Image: %s\n", feed.ImageUrl.String)
+		}
+		if feed.Generator.Valid {
+			fmt.Printf("  Generator: %s\n", feed.Generator.String)
+		}
+		if feed.LastBuildDate.Valid {
+			fmt.Printf("  Last updated: %s\n", feed.LastBuildDate.Time.Format("January 2, 2006 at 3:04 PM"))
+		}
 		fmt.Printf("  Created by: %s\n\n", feed.UserName)
 	}
 	return nil
@@ -259,6 +646,9 @@ func HandlerFollow(s *State, cmd Command, user database.User) error {
 	ctx := context.Background()
 
 	feed, err := s.DB.GetFeedByUrl(ctx, url)
+	if err == sql.ErrNoRows {
+		feed, err = findFollowableFeed(ctx, s, url)
+	}
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return fmt.Errorf("feed not found")
@@ -281,6 +671,47 @@ func HandlerFollow(s *State, cmd Command, user database.User) error {
 	return nil
 }
 
+// findFollowableFeed handles following by a site's homepage: the page's feeds
+// are discovered and matched against the ones already added
+func findFollowableFeed(ctx context.Context, s *State, pageURL string) (database.Feed, error) {
+	fetcher, err := newFeedFetcher(s.Cfg)
+	if err != nil {
+		return database.Feed{}, err
+	}
+	candidates, err := feedCandidates(ctx, fetcher, pageURL, fetchOptions{})
+	if err != nil {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	for _, candidate := range candidates {
+		if candidate.Stream != nil {
+			candidate.Stream.Close()
+		}
+	}
+
+	var known []feedCandidate
+	feeds := make(map[string]database.Feed)
+	for _, candidate := range candidates {
+		feed, err := s.DB.GetFeedByUrl(ctx, candidate.URL)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return database.Feed{}, err
+		}
+		known = append(known, feedCandidate{URL: feed.Url, Title: feed.Name})
+		feeds[feed.Url] = feed
+	}
+	if len(known) == 0 {
+		return database.Feed{}, sql.ErrNoRows
+	}
+
+	candidate, err := chooseFeed(known)
+	if err != nil {
+		return database.Feed{}, err
+	}
+	return feeds[candidate.URL], nil
+}
+
 func HandlerFollowing(s *State, cmd Command, user database.User) error {
 	if len(cmd.Args) != 0 {
 		return fmt.Errorf("usage: following")
@@ -294,7 +725,19 @@ func HandlerFollowing(s *State, cmd Command, user database.User) error {
 
 	fmt.Println("Following feeds:")
 	for _, f := range follows {
-		fmt.Printf("* %s\n", f.FeedName)
+		fmt.Printf("* %s (%s)\n", f.FeedName, f.FeedUrl)
+		if f.SiteUrl.Valid {
+			fmt.Printf("  Site: %s\n", f.SiteUrl.String)
+		}
+		if f.Description.Valid {
+			fmt.Printf("  %s\n", truncateText(htmlToText(f.Description.String), 200))
+		}
+		if f.Language.Valid {
+			fmt.Printf("  Language: %s\n", f.Language.String)
+		}
+		if f.LastBuildDate.Valid {
+			fmt.Printf("  Last updated: %s\n", f.LastBuildDate.Time.Format("January 2, 2006 at 3:04 PM"))
+		}
 	}
 	return nil
 }
@@ -318,7 +761,7 @@ func HandlerUnfollow(s *State, cmd Command, user database.User) error {
 	// Delete the feed follow record
 	err = s.DB.DeleteFeedFollow(ctx, database.DeleteFeedFollowParams{
 		UserID: user.ID,
-		Url:    url,
+		FeedID: feed.ID,
 	})
 	if err != nil {
 		return fmt.Errorf("error unfollowing feed: %v", err)
@@ -330,28 +773,45 @@ func HandlerUnfollow(s *State, cmd Command, user database.User) error {
 
 func HandlerBrowse(s *State, cmd Command, user database.User) error {
 	limit := 2 // Default limit
-	if len(cmd.Args) > 0 {
-		parsedLimit, err := strconv.Atoi(cmd.Args[0])
-		if err != nil {
-			return fmt.Errorf("invalid limit: %v", err)
-		}
-		if parsedLimit <= 0 {
-			return fmt.Errorf("limit must be positive")
+	var category, author sql.NullString
+	for i := 0; i < len(cmd.Args); i++ {
+		switch arg := cmd.Args[i]; arg {
+		case "--category", "--author":
+			if i+1 >= len(cmd.Args) {
+				return fmt.Errorf("%s requires a value", arg)
+			}
+			i++
+			value := sql.NullString{String: cmd.Args[i], Valid: true}
+			if arg == "--category" {
+				category = value
+			} else {
+				author = value
+			}
+		default:
+			parsedLimit, err := strconv.Atoi(arg)
+			if err != nil {
+				return fmt.Errorf("invalid limit: %v", err)
+			}
+			if parsedLimit <= 0 {
+				return fmt.Errorf("limit must be positive")
+			}
+			limit = parsedLimit
 		}
-		limit = parsedLimit
 	}
 
 	ctx := context.Background()
 	posts, err := s.DB.GetPostsForUser(ctx, database.GetPostsForUserParams{
-		UserID: user.ID,
-		Limit:  int32(limit),
+		UserID:   user.ID,
+		Category: category,
+		Author:   author,
+		Limit:    int32(limit),
 	})
 	if err != nil {
 		return fmt.Errorf("error getting posts: %v", err)
 	}
 
 	if len(posts) == 0 {
-		fmt.Println("No posts found from your followed feeds")
+		fmt.Println("No matching posts found from your followed feeds")
 		return nil
 	}
 
@@ -359,14 +819,95 @@ func HandlerBrowse(s *State, cmd Command, user database.User) error {
 	for _, post := range posts {
 		fmt.Printf("Title: %s\n", post.Title)
 		fmt.Printf("Feed: %s\n", post.FeedName)
+
+		authors, err := s.DB.GetAuthorsForPost(ctx, post.ID)
+		if err != nil {
+			return fmt.Errorf("error getting authors: %v", err)
+		}
+		if len(authors) > 0 {
+			names := make([]string, 0, len(authors))
+			for _, a := range authors {
+				names = append(names, a.Name)
+			}
+			fmt.Printf("Authors: %s\n", strings.Join(names, ", "))
+		}
+
+		categories, err := s.DB.GetCategoriesForPost(ctx, post.ID)
+		if err != nil {
+			return fmt.Errorf("error getting categories: %v", err)
+		}
+		if len(categories) > 0 {
+			names := make([]string, 0, len(categories))
+			for _, c := range categories {
+				names = append(names, c.Name)
+			}
+			fmt.Printf("Categories: %s\n", strings.Join(names, ", "))
+		}
+
 		if post.Description.Valid && post.Description.String != "" {
 			fmt.Printf("Description: %s\n", post.Description.String)
+		} else if post.Content.Valid && post.Content.String != "" {
+			// No teaser in the feed, so show the start of the full article instead
+			fmt.Printf("Description: %s\n", truncateText(htmlToText(post.Content.String), 280))
 		}
 		fmt.Printf("URL: %s\n", post.Url)
 		if post.PublishedAt.Valid {
 			fmt.Printf("Published: %s\n", post.PublishedAt.Time.Format("January 2, 2006 at 3:04 PM"))
 		}
+		enclosures, err := s.DB.GetEnclosuresForPost(ctx, post.ID)
+		if err != nil {
+			return fmt.Errorf("error getting enclosures: %v", err)
+		}
+		for _, enc := range enclosures {
+			fmt.Printf("Media: %s\n", describeEnclosure(enc))
+		}
+		mediaItems, err := s.DB.GetMediaItemsForPost(ctx, post.ID)
+		if err != nil {
+			return fmt.Errorf("error getting media: %v", err)
+		}
+		if len(mediaItems) > 0 {
+			fmt.Printf("Preview: %s\n", describeMedia(mediaItems))
+		}
+		if post.Content.Valid && post.Content.String != "" {
+			fmt.Printf("Full article: gator read %s\n", post.ID)
+		}
 		fmt.Println("=====================================")
 	}
 	return nil
 }
+
+func HandlerRead(s *State, cmd Command) error {
+	if len(cmd.Args) < 1 {
+		return fmt.Errorf("usage: read <post-id>")
+	}
+	postID, err := uuid.Parse(cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("invalid post id: %v", err)
+	}
+
+	post, err := s.DB.GetPostByID(context.Background(), postID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("post not found")
+		}
+		return fmt.Errorf("error getting post: %v", err)
+	}
+
+	fmt.Printf("Title: %s\n", post.Title)
+	fmt.Printf("Feed: %s\n", post.FeedName)
+	fmt.Printf("URL: %s\n", post.Url)
+	if post.PublishedAt.Valid {
+		fmt.Printf("Published: %s\n", post.PublishedAt.Time.Format("January 2, 2006 at 3:04 PM"))
+	}
+	fmt.Println("=====================================")
+
+	switch {
+	case post.Content.Valid && post.Content.String != "":
+		fmt.Println(htmlToText(post.Content.String))
+	case post.Description.Valid && post.Description.String != "":
+		fmt.Println(htmlToText(post.Description.String))
+	default:
+		fmt.Println("This post has no content, open the URL to read it")
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// HandlerBroken lists feeds whose recent fetches failed, including the ones
+// that have been disabled or retired
+func HandlerBroken(s *State, cmd Command) error {
+	feeds, err := s.DB.ListBrokenFeeds(context.Background())
+	if err != nil {
+		return fmt.Errorf("error listing broken feeds: %v", err)
+	}
+	if len(feeds) == 0 {
+		fmt.Printf("All feeds are healthy\n")
+		return nil
+	}
+
+	fmt.Printf("Broken feeds:\n")
+	for _, feed := range feeds {
+		status := "failing"
+		if feed.RetiredAt.Valid {
+			status = "retired, the server says it's gone"
+		} else if feed.Disabled {
+			status = "disabled"
+		}
+		fmt.Printf("  Name: %s (%s)\n", feed.Name, status)
+		fmt.Printf("  URL: %s\n", feed.Url)
+		fmt.Printf("  Failures in a row: %d\n", feed.ConsecutiveFailures)
+		if feed.LastError.Valid {
+			fmt.Printf("  Last error: %s\n", feed.LastError.String)
+		}
+		if feed.LastSuccessAt.Valid {
+			fmt.Printf("  Last success: %s\n", feed.LastSuccessAt.Time.Format("January 2, 2006 at 3:04 PM"))
+		} else {
+			fmt.Printf("  Last success: never\n")
+		}
+		if !feed.Disabled && !feed.RetiredAt.Valid && feed.NextFetchAt.Valid {
+			fmt.Printf("  Next retry: %s\n", feed.NextFetchAt.Time.Format("January 2, 2006 at 3:04 PM"))
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// HandlerEnable puts a disabled or retired feed back into rotation; its
+// failure count starts over and it's fetched on the next agg run
+func HandlerEnable(s *State, cmd Command) error {
+	if len(cmd.Args) < 1 {
+		return fmt.Errorf("usage: enable <feed-url>")
+	}
+	ctx := context.Background()
+
+	feed, err := s.DB.GetFeedByUrl(ctx, cmd.Args[0])
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("feed not found")
+		}
+		return fmt.Errorf("error getting feed: %v", err)
+	}
+
+	if err := s.DB.EnableFeed(ctx, feed.ID); err != nil {
+		return fmt.Errorf("error enabling feed: %v", err)
+	}
+	fmt.Printf("Enabled feed %s\n", feed.Name)
+	return nil
+}
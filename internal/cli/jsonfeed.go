@@ -0,0 +1,284 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// JSONFeed covers both version 1.0 and 1.1 of https://www.jsonfeed.org/version/1.1/
+type JSONFeed struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageURL string           `json:"home_page_url"`
+	FeedURL     string           `json:"feed_url"`
+	Description string           `json:"description"`
+	Icon        string           `json:"icon"`
+	Favicon     string           `json:"favicon"`
+	Language    string           `json:"language"`
+	Authors     []JSONFeedAuthor `json:"authors"`
+	Author      *JSONFeedAuthor  `json:"author"`
+}
+
+type JSONFeedItem struct {
+	ID            jsonFeedID       `json:"id"`
+	URL           string           `json:"url"`
+	ExternalURL   string           `json:"external_url"`
+	Title         string           `json:"title"`
+	ContentHTML   string           `json:"content_html"`
+	ContentText   string           `json:"content_text"`
+	Summary       string           `json:"summary"`
+	DatePublished string           `json:"date_published"`
+	DateModified  string           `json:"date_modified"`
+	Authors       []JSONFeedAuthor `json:"authors"`
+	Author        *JSONFeedAuthor  `json:"author"` // deprecated in 1.1 but still common
+	Tags          []string         `json:"tags"`
+	Attachments   []struct {
+		URL               string  `json:"url"`
+		MimeType          string  `json:"mime_type"`
+		SizeInBytes       int64   `json:"size_in_bytes"`
+		DurationInSeconds float64 `json:"duration_in_seconds"`
+	} `json:"attachments"`
+	Image       string `json:"image"`
+	BannerImage string `json:"banner_image"`
+}
+
+// jsonFeedID is an item's id, which the spec says must be a string but also
+// that numbers given instead should be turned into one
+type jsonFeedID string
+
+func (id *jsonFeedID) UnmarshalJSON(data []byte) error {
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err == nil {
+		*id = jsonFeedID(number.String())
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("item id must be a string or a number")
+	}
+	*id = jsonFeedID(s)
+	return nil
+}
+
+type JSONFeedAuthor struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Avatar string `json:"avatar"`
+}
+
+// jsonFeedReader reads a JSON Feed a token at a time, decoding the members
+// of the top-level object as they come and the items one at a time
+type jsonFeedReader struct {
+	decoder *json.Decoder
+	feed    JSONFeed
+	inItems bool
+	held    []json.RawMessage // items that came before the version, kept until it's checked
+	ended   bool              // the top-level object has been closed
+}
+
+func newJSONFeedReader(doc io.Reader) (*jsonFeedReader, error) {
+	r := &jsonFeedReader{decoder: json.NewDecoder(doc)}
+	tok, err := r.token()
+	if err != nil {
+		return nil, fmt.Errorf("error reading JSON Feed: %w", err)
+	}
+	if tok != json.Delim('{') {
+		return nil, fmt.Errorf("error reading JSON Feed: not an object")
+	}
+	return r, nil
+}
+
+func (r *jsonFeedReader) header() *ParsedFeed {
+	feed := &ParsedFeed{
+		Title:       r.feed.Title,
+		Link:        r.feed.HomePageURL,
+		Description: r.feed.Description,
+		Language:    r.feed.Language,
+		Image:       r.feed.Icon,
+	}
+	if feed.Image == "" {
+		feed.Image = r.feed.Favicon
+	}
+	return feed
+}
+
+func (r *jsonFeedReader) next() (*FeedItem, error) {
+	for {
+		if r.ended {
+			if len(r.held) == 0 {
+				return nil, io.EOF
+			}
+			var item JSONFeedItem
+			err := json.Unmarshal(r.held[0], &item)
+			r.held = r.held[1:]
+			if err != nil {
+				return nil, err
+			}
+			feedItem := item.toFeedItem(&r.feed)
+			return &feedItem, nil
+		}
+
+		if r.inItems {
+			if r.decoder.More() {
+				var item JSONFeedItem
+				if err := r.decoder.Decode(&item); err != nil {
+					return nil, err
+				}
+				feedItem := item.toFeedItem(&r.feed)
+				return &feedItem, nil
+			}
+			// The end of the items array
+			if _, err := r.token(); err != nil {
+				return nil, err
+			}
+			r.inItems = false
+		}
+
+		tok, err := r.token()
+		if err != nil {
+			return nil, err
+		}
+		if tok == json.Delim('}') {
+			if err := r.checkVersion(); err != nil {
+				return nil, err
+			}
+			r.ended = true
+			continue
+		}
+
+		switch key, _ := tok.(string); key {
+		case "items":
+			tok, err := r.token()
+			if err != nil {
+				return nil, err
+			}
+			if tok != json.Delim('[') {
+				return nil, fmt.Errorf("items is not an array")
+			}
+			if r.feed.Version != "" {
+				// Items are saved as soon as they're read, so any other JSON
+				// with an items array has to be turned away before the first one
+				if err := r.checkVersion(); err != nil {
+					return nil, err
+				}
+				r.inItems = true
+				continue
+			}
+			// Nothing says where the version goes, and serializers that sort
+			// their keys put it after the items, so they wait until it's known
+			for r.decoder.More() {
+				var item json.RawMessage
+				if err := r.decoder.Decode(&item); err != nil {
+					return nil, err
+				}
+				r.held = append(r.held, item)
+			}
+			if _, err := r.token(); err != nil {
+				return nil, err
+			}
+		default:
+			// Decode the member through JSONFeed so its struct tags apply
+			var value json.RawMessage
+			if err := r.decoder.Decode(&value); err != nil {
+				return nil, err
+			}
+			member, err := json.Marshal(map[string]json.RawMessage{key: value})
+			if err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal(member, &r.feed); err != nil {
+				return nil, fmt.Errorf("error unmarshaling JSON Feed: %v", err)
+			}
+		}
+	}
+}
+
+// token reads the next token; the document running out before the top-level
+// object is closed means it was cut short
+func (r *jsonFeedReader) token() (json.Token, error) {
+	tok, err := r.decoder.Token()
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return tok, err
+}
+
+func (r *jsonFeedReader) checkVersion() error {
+	if !strings.HasPrefix(r.feed.Version, "https://jsonfeed.org/version/1") {
+		return fmt.Errorf("unsupported JSON Feed version: %q", r.feed.Version)
+	}
+	return nil
+}
+
+// toFeedItem converts an item; items without their own author inherit the
+// feed's
+func (item *JSONFeedItem) toFeedItem(feed *JSONFeed) FeedItem {
+	link := item.URL
+	if link == "" {
+		link = item.ExternalURL
+	}
+
+	content := item.ContentHTML
+	if content == "" {
+		content = item.ContentText
+	}
+
+	pubDate := item.DatePublished
+	if pubDate == "" {
+		pubDate = item.DateModified
+	}
+
+	people := item.Authors
+	if len(people) == 0 && item.Author != nil {
+		people = []JSONFeedAuthor{*item.Author}
+	}
+	if len(people) == 0 {
+		people = feed.Authors
+	}
+	if len(people) == 0 && feed.Author != nil {
+		people = []JSONFeedAuthor{*feed.Author}
+	}
+	var authors []FeedAuthor
+	for _, person := range people {
+		if person.Name != "" {
+			authors = append(authors, FeedAuthor{Name: person.Name})
+		}
+	}
+
+	var enclosures []FeedEnclosure
+	for _, attachment := range item.Attachments {
+		if attachment.URL == "" {
+			continue
+		}
+		enclosures = append(enclosures, FeedEnclosure{
+			URL:      attachment.URL,
+			Type:     attachment.MimeType,
+			Length:   attachment.SizeInBytes,
+			Duration: int(attachment.DurationInSeconds),
+			Image:    item.Image,
+		})
+	}
+
+	var media []FeedMedia
+	if item.Image != "" {
+		media = append(media, FeedMedia{URL: item.Image, Medium: "image", Thumbnail: item.Image})
+	}
+	if item.BannerImage != "" && item.BannerImage != item.Image {
+		media = append(media, FeedMedia{URL: item.BannerImage, Medium: "image"})
+	}
+
+	return FeedItem{
+		GUID:        string(item.ID),
+		Title:       item.Title,
+		Link:        link,
+		Description: item.Summary,
+		Content:     content,
+		PubDate:     strings.TrimSpace(pubDate),
+		Authors:     authors,
+		Categories:  item.Tags,
+		Enclosures:  enclosures,
+		Media:       media,
+	}
+}
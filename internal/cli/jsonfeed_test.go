@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONFeedReader(t *testing.T) {
+	tests := []struct {
+		name      string
+		doc       string
+		wantFeed  ParsedFeed
+		wantItems []FeedItem
+	}{
+		{
+			name: "version 1.1",
+			doc: `{
+				"version": "https://jsonfeed.org/version/1.1",
+				"title": "Tom &amp; Jerry",
+				"home_page_url": "https://example.org/blog/",
+				"feed_url": "https://example.org/blog/feed.json",
+				"description": "A blog",
+				"icon": "/icon.png",
+				"favicon": "/favicon.ico",
+				"language": "en-GB",
+				"authors": [{"name": "Tom"}],
+				"items": [
+					{
+						"id": "post-1",
+						"url": "posts/1",
+						"title": "First &lt;post&gt;",
+						"content_html": "<p><a href=\"/about\">About</a></p>",
+						"content_text": "About",
+						"summary": "The first",
+						"date_published": " 2024-05-01T10:00:00Z ",
+						"date_modified": "2024-05-02T10:00:00Z",
+						"tags": ["news", "go"],
+						"image": "img/1.jpg",
+						"banner_image": "img/1-wide.jpg",
+						"attachments": [
+							{"url": "ep1.mp3", "mime_type": "audio/mpeg", "size_in_bytes": 1234, "duration_in_seconds": 61.5},
+							{"mime_type": "audio/mpeg"}
+						]
+					},
+					{
+						"id": 42,
+						"external_url": "https://elsewhere.example/42",
+						"content_text": "Plain text",
+						"date_modified": "2024-05-03T10:00:00Z",
+						"authors": [{"name": "Jerry"}, {"url": "https://nameless.example"}]
+					}
+				]
+			}`,
+			wantFeed: ParsedFeed{
+				Title:       "Tom & Jerry",
+				Link:        "https://example.org/blog/",
+				Description: "A blog",
+				Language:    "en-GB",
+				Image:       "https://example.org/icon.png",
+				Encoding:    "utf-8",
+			},
+			wantItems: []FeedItem{
+				{
+					GUID:        "post-1",
+					Title:       "First <post>",
+					Link:        "https://example.org/blog/posts/1",
+					Description: "The first",
+					Content:     `<p><a href="https://example.org/about">About</a></p>`,
+					PubDate:     "2024-05-01T10:00:00Z",
+					Authors:     []FeedAuthor{{Name: "Tom"}},
+					Categories:  []string{"news", "go"},
+					Enclosures: []FeedEnclosure{{
+						URL:      "https://example.org/blog/ep1.mp3",
+						Type:     "audio/mpeg",
+						Length:   1234,
+						Duration: 61,
+						Image:    "https://example.org/blog/img/1.jpg",
+					}},
+					Media: []FeedMedia{
+						{URL: "https://example.org/blog/img/1.jpg", Medium: "image", Thumbnail: "https://example.org/blog/img/1.jpg"},
+						{URL: "https://example.org/blog/img/1-wide.jpg", Medium: "image"},
+					},
+				},
+				{
+					GUID:    "42",
+					Link:    "https://elsewhere.example/42",
+					Content: "Plain text",
+					PubDate: "2024-05-03T10:00:00Z",
+					Authors: []FeedAuthor{{Name: "Jerry"}},
+				},
+			},
+		},
+		{
+			name: "version 1 with a single author",
+			doc: `{"version": "https://jsonfeed.org/version/1", "title": "Old", "favicon": "https://example.org/favicon.ico",
+				"author": {"name": "Ann"},
+				"items": [{"id": "1", "url": "https://example.org/1", "title": "One"},
+					{"id": "2", "url": "https://example.org/2", "author": {"name": "Bob"}}]}`,
+			wantFeed: ParsedFeed{Title: "Old", Image: "https://example.org/favicon.ico", Encoding: "utf-8"},
+			wantItems: []FeedItem{
+				{GUID: "1", Title: "One", Link: "https://example.org/1", Authors: []FeedAuthor{{Name: "Ann"}}},
+				{GUID: "2", Link: "https://example.org/2", Authors: []FeedAuthor{{Name: "Bob"}}},
+			},
+		},
+		{
+			// As written by anything that sorts its keys, e.g. jq -S
+			name: "keys out of order",
+			doc: `{"items": [{"id": "a", "url": "/a"}, {"id": "b", "url": "/b"}],
+				"home_page_url": "https://example.org/",
+				"title": "Sorted",
+				"version": "https://jsonfeed.org/version/1.1"}`,
+			wantFeed: ParsedFeed{Title: "Sorted", Link: "https://example.org/", Encoding: "utf-8"},
+			wantItems: []FeedItem{
+				{GUID: "a", Link: "https://example.org/a"},
+				{GUID: "b", Link: "https://example.org/b"},
+			},
+		},
+		{
+			name:     "no items",
+			doc:      `{"title": "Empty", "version": "https://jsonfeed.org/version/1.1", "items": []}`,
+			wantFeed: ParsedFeed{Title: "Empty", Encoding: "utf-8"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, items, err := readFeed(t, tt.doc, "application/feed+json")
+			if err != nil {
+				t.Fatalf("reading feed: %v", err)
+			}
+			if !reflect.DeepEqual(*feed, tt.wantFeed) {
+				t.Errorf("feed = %+v, want %+v", *feed, tt.wantFeed)
+			}
+			if len(items) != len(tt.wantItems) {
+				t.Fatalf("got %d items, want %d", len(items), len(tt.wantItems))
+			}
+			for i := range items {
+				if !reflect.DeepEqual(items[i], tt.wantItems[i]) {
+					t.Errorf("item %d = %+v, want %+v", i, items[i], tt.wantItems[i])
+				}
+			}
+		})
+	}
+}
+
+func TestJSONFeedReaderInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		wantErr string
+	}{
+		{"not an object", `[{"id": "1"}]`, "not an object"},
+		{"no version", `{"title": "T", "items": [{"id": "1"}]}`, `unsupported JSON Feed version: ""`},
+		{"no version after the items", `{"items": [{"id": "1"}], "title": "T"}`, `unsupported JSON Feed version: ""`},
+		{"other version", `{"version": "https://jsonfeed.org/version/2", "items": [{"id": "1"}]}`, "unsupported JSON Feed version"},
+		{"items not an array", `{"version": "https://jsonfeed.org/version/1.1", "items": {}}`, "items is not an array"},
+		{"bad id", `{"version": "https://jsonfeed.org/version/1.1", "items": [{"id": {}}]}`, "item id must be a string or a number"},
+		{"bad id before the version", `{"items": [{"id": true}], "version": "https://jsonfeed.org/version/1.1"}`, "item id must be a string or a number"},
+		{"cut short", `{"version": "https://jsonfeed.org/version/1.1", "items": [`, "unexpected end"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, items, err := readFeed(t, tt.doc, "application/feed+json")
+			if err == nil {
+				t.Fatalf("expected an error, got %d items", len(items))
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to mention %q", err, tt.wantErr)
+			}
+			if len(items) > 0 {
+				t.Errorf("got %d items from a document that isn't a JSON Feed", len(items))
+			}
+		})
+	}
+}
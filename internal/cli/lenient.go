@@ -0,0 +1,196 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"unicode/utf8"
+)
+
+var entityRefPattern = regexp.MustCompile(`^&(#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[A-Za-z][A-Za-z0-9]{0,31});`)
+
+// lenientAutoClose is xml.HTMLAutoClose without "link", which is a void
+// element in HTML but holds the item's URL in RSS
+var lenientAutoClose = func() []string {
+	var names []string
+	for _, name := range xml.HTMLAutoClose {
+		if name != "link" {
+			names = append(names, name)
+		}
+	}
+	return names
+}()
+
+// sanitizeLookahead is how far ahead the sanitizer needs to see to recognise
+// a CDATA marker, an entity reference or a multi-byte character
+const sanitizeLookahead = 64
+
+// sanitizingReader fixes the mistakes that make most real feeds fail to
+// parse as the document streams through it: HTML entities XML doesn't
+// define, bare ampersands, control characters and invalid UTF-8. CDATA
+// sections are copied untouched apart from the last two.
+type sanitizingReader struct {
+	r   *bufio.Reader
+	buf []byte
+	out []byte // the part of buf not read yet
+	err error
+
+	inCDATA                  bool
+	entities, ampersands     int
+	controlChars, badEncoded int
+}
+
+func newSanitizingReader(r io.Reader) *sanitizingReader {
+	return &sanitizingReader{r: bufio.NewReaderSize(r, 4096)}
+}
+
+func (s *sanitizingReader) Read(p []byte) (int, error) {
+	for len(s.out) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		s.fill()
+	}
+	n := copy(p, s.out)
+	s.out = s.out[n:]
+	return n, nil
+}
+
+// fill sanitizes the next chunk of input, leaving enough of it unread that
+// nothing gets cut in half until the input runs out
+func (s *sanitizingReader) fill() {
+	chunk, err := s.r.Peek(4096)
+	end := len(chunk) - sanitizeLookahead
+	if err != nil {
+		end = len(chunk)
+	}
+
+	s.out = s.buf[:0]
+	i := 0
+	for i < end {
+		rest := chunk[i:]
+		if c := rest[0]; c >= 0x20 && c < utf8.RuneSelf && c != '&' && c != '<' && c != ']' {
+			s.out = append(s.out, c)
+			i++
+			continue
+		}
+		i += s.sanitize(rest)
+	}
+	s.buf = s.out
+	s.r.Discard(i)
+	if err != nil {
+		s.err = err
+	}
+}
+
+// sanitize copies what's at the start of rest to the output, repaired if
+// need be, and returns how many bytes of input it used up
+func (s *sanitizingReader) sanitize(rest []byte) int {
+	switch {
+	case !s.inCDATA && bytes.HasPrefix(rest, []byte("<![CDATA[")):
+		s.inCDATA = true
+		s.out = append(s.out, "<![CDATA["...)
+		return len("<![CDATA[")
+	case s.inCDATA && bytes.HasPrefix(rest, []byte("]]>")):
+		s.inCDATA = false
+		s.out = append(s.out, "]]>"...)
+		return len("]]>")
+	}
+
+	r, size := utf8.DecodeRune(rest)
+	switch {
+	case r == utf8.RuneError && size == 1:
+		s.out = utf8.AppendRune(s.out, utf8.RuneError)
+		s.badEncoded++
+	case !isXMLChar(r):
+		s.controlChars++
+	case r == '&' && !s.inCDATA:
+		m := entityRefPattern.FindSubmatch(rest)
+		if m == nil {
+			s.out = append(s.out, "&amp;"...)
+			s.ampersands++
+			break
+		}
+		ref := string(m[1])
+		size = len(m[0])
+		switch {
+		case ref[0] == '#':
+			if isXMLChar(parseCharRef(ref)) {
+				s.out = append(s.out, m[0]...)
+			} else {
+				s.controlChars++
+			}
+		case ref == "amp" || ref == "lt" || ref == "gt" || ref == "quot" || ref == "apos":
+			s.out = append(s.out, m[0]...)
+		case xml.HTMLEntity[ref] != "":
+			for _, c := range xml.HTMLEntity[ref] {
+				s.out = fmt.Appendf(s.out, "&#%d;", c)
+			}
+			s.entities++
+		default:
+			// Not an entity anyone defines, so the & was meant literally
+			s.out = append(s.out, "&amp;"...)
+			s.ampersands++
+			size = 1
+		}
+	default:
+		s.out = append(s.out, rest[:size]...)
+	}
+	return size
+}
+
+// repairs describes what the sanitizer has fixed so far
+func (s *sanitizingReader) repairs() []string {
+	var repairs []string
+	if s.entities > 0 {
+		repairs = append(repairs, fmt.Sprintf("replaced %d HTML entities", s.entities))
+	}
+	if s.ampersands > 0 {
+		repairs = append(repairs, fmt.Sprintf("escaped %d bare ampersands", s.ampersands))
+	}
+	if s.controlChars > 0 {
+		repairs = append(repairs, fmt.Sprintf("removed %d invalid control characters", s.controlChars))
+	}
+	if s.badEncoded > 0 {
+		repairs = append(repairs, fmt.Sprintf("replaced %d invalid UTF-8 sequences", s.badEncoded))
+	}
+	return repairs
+}
+
+// isSyntaxError reports whether err means the document itself is broken,
+// as opposed to it failing to arrive
+func isSyntaxError(err error) bool {
+	var xmlErr *xml.SyntaxError
+	var jsonErr *json.SyntaxError
+	return errors.As(err, &xmlErr) || errors.As(err, &jsonErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func parseCharRef(ref string) rune {
+	var (
+		n   uint64
+		err error
+	)
+	if len(ref) > 1 && (ref[1] == 'x' || ref[1] == 'X') {
+		n, err = strconv.ParseUint(ref[2:], 16, 32)
+	} else {
+		n, err = strconv.ParseUint(ref[1:], 10, 32)
+	}
+	if err != nil {
+		return -1
+	}
+	return rune(n)
+}
+
+// isXMLChar reports whether r is allowed in an XML 1.0 document
+func isXMLChar(r rune) bool {
+	return r == 0x09 || r == 0x0A || r == 0x0D ||
+		r >= 0x20 && r <= 0xD7FF ||
+		r >= 0xE000 && r <= 0xFFFD ||
+		r >= 0x10000 && r <= 0x10FFFF
+}
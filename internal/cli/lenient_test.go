@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func sanitize(t *testing.T, r io.Reader) (string, *sanitizingReader) {
+	t.Helper()
+	s := newSanitizingReader(r)
+	out, err := io.ReadAll(s)
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+	return string(out), s
+}
+
+func TestSanitizingReader(t *testing.T) {
+	tests := []struct {
+		name        string
+		in          string
+		want        string
+		wantRepairs []string
+	}{
+		{"clean", `<rss><title>A &amp; B &lt;3 &#233; &#xE9;</title></rss>`,
+			`<rss><title>A &amp; B &lt;3 &#233; &#xE9;</title></rss>`, nil},
+		{"html entities", "<t>&nbsp;&eacute;&mdash;</t>", "<t>&#160;&#233;&#8212;</t>",
+			[]string{"replaced 3 HTML entities"}},
+		{"bare ampersands", "<t>Q&A, fish & chips, AT&T</t>", "<t>Q&amp;A, fish &amp; chips, AT&amp;T</t>",
+			[]string{"escaped 3 bare ampersands"}},
+		{"unknown entity", "<link>?a=1&foo;</link>", "<link>?a=1&amp;foo;</link>",
+			[]string{"escaped 1 bare ampersands"}},
+		{"ampersand at the end", "<t>a&", "<t>a&amp;", []string{"escaped 1 bare ampersands"}},
+		{"control characters", "<t>a\x00b\x08c\x1Fd\te\nf\rg</t>", "<t>abcd\te\nf\rg</t>",
+			[]string{"removed 3 invalid control characters"}},
+		{"invalid char refs", "<t>&#0;&#x1B;&#xFFFE;&#x110000;&#65;</t>", "<t>&#65;</t>",
+			[]string{"removed 4 invalid control characters"}},
+		{"invalid UTF-8", "<t>caf\xe9 \xff\xfe ok\xc3\xa9</t>", "<t>caf� �� oké</t>",
+			[]string{"replaced 3 invalid UTF-8 sequences"}},
+		{"truncated multi-byte character", "<t>\xe2\x82", "<t>��",
+			[]string{"replaced 2 invalid UTF-8 sequences"}},
+		{"CDATA untouched", "<d><![CDATA[<p>Q&A &nbsp; &amp;</p>]]> &nbsp;</d>",
+			"<d><![CDATA[<p>Q&A &nbsp; &amp;</p>]]> &#160;</d>", []string{"replaced 1 HTML entities"}},
+		{"CDATA still loses control characters", "<![CDATA[a\x01b\xffc]]>", "<![CDATA[ab�c]]>",
+			[]string{"removed 1 invalid control characters", "replaced 1 invalid UTF-8 sequences"}},
+		{"stray CDATA end outside CDATA", "<t>]]&gt; ]]> &copy;</t>", "<t>]]&gt; ]]> &#169;</t>",
+			[]string{"replaced 1 HTML entities"}},
+		{"everything", "<t>&hellip;&\x0C\x80</t>", "<t>&#8230;&amp;�</t>", []string{
+			"replaced 1 HTML entities",
+			"escaped 1 bare ampersands",
+			"removed 1 invalid control characters",
+			"replaced 1 invalid UTF-8 sequences",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, s := sanitize(t, strings.NewReader(tt.in))
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if repairs := s.repairs(); !reflect.DeepEqual(repairs, tt.wantRepairs) {
+				t.Errorf("repairs = %q, want %q", repairs, tt.wantRepairs)
+			}
+		})
+	}
+}
+
+// TestSanitizingReaderChunkBoundary moves each kind of repair across the end
+// of the 4096-byte chunks the sanitizer works on, where it can only see
+// sanitizeLookahead bytes ahead, and feeds the input in small reads too
+func TestSanitizingReaderChunkBoundary(t *testing.T) {
+	pieces := []struct {
+		name, in, want string
+	}{
+		{"entity", "&mdash;", "&#8212;"},
+		{"long entity", "&thetasym;", "&#977;"},
+		{"char ref", "&#x1F600;", "&#x1F600;"},
+		{"bare ampersand", "& ", "&amp; "},
+		{"multi-byte character", "\xf0\x9f\x98\x80", "😀"},
+		{"CDATA", "<![CDATA[&nbsp;&]]>&nbsp;", "<![CDATA[&nbsp;&]]>&#160;"},
+		{"invalid UTF-8", "\xe9\xe9", "��"},
+	}
+	readers := map[string]func(io.Reader) io.Reader{
+		"whole":       func(r io.Reader) io.Reader { return r },
+		"byte a time": iotest.OneByteReader,
+		"half reads":  iotest.HalfReader,
+		"data at EOF": iotest.DataErrReader,
+	}
+
+	const chunk = 4096
+	for _, piece := range pieces {
+		for pad := chunk - sanitizeLookahead - 12; pad <= chunk+12; pad++ {
+			padding := strings.Repeat("x", pad)
+			// Repeat the piece so it also lands near the end of the second chunk
+			in := padding + piece.in + padding + piece.in + "</t>"
+			want := padding + piece.want + padding + piece.want + "</t>"
+			for readerName, wrap := range readers {
+				got, _ := sanitize(t, wrap(strings.NewReader(in)))
+				if got != want {
+					i := firstDifference(got, want)
+					t.Fatalf("%s at %d, %s: from byte %d got %q, want %q", piece.name, pad, readerName,
+						i, excerpt(got, i), excerpt(want, i))
+				}
+			}
+		}
+	}
+}
+
+func firstDifference(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+func excerpt(s string, i int) string {
+	return s[min(i, len(s)):min(i+32, len(s))]
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"strings"
+
+	"github.com/voidarchive/Gator/internal/database"
+)
+
+// mediaRSS holds the Media RSS (http://www.rssboard.org/media-rss) elements
+// an RSS item or Atom entry can carry, either directly or inside media:group
+type mediaRSS struct {
+	MediaContents   []mediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaThumbnails []mediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	MediaGroups     []struct {
+		Contents   []mediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+		Thumbnails []mediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	} `xml:"http://search.yahoo.com/mrss/ group"`
+}
+
+type mediaContent struct {
+	URL        string           `xml:"url,attr"`
+	Type       string           `xml:"type,attr"`
+	Medium     string           `xml:"medium,attr"`
+	Width      string           `xml:"width,attr"`
+	Height     string           `xml:"height,attr"`
+	Thumbnails []mediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+type mediaThumbnail struct {
+	URL    string `xml:"url,attr"`
+	Width  string `xml:"width,attr"`
+	Height string `xml:"height,attr"`
+}
+
+// FeedMedia is a picture or video attached to an item, with a preview image
+// a frontend can show in place of the media itself
+type FeedMedia struct {
+	URL       string
+	Type      string
+	Medium    string // image, video, audio, ... when the feed says
+	Thumbnail string
+	Width     int
+	Height    int
+}
+
+func (m *mediaRSS) toFeedMedia() []FeedMedia {
+	var media []FeedMedia
+	add := func(contents []mediaContent, thumbnails []mediaThumbnail) {
+		for _, c := range contents {
+			if c.URL == "" {
+				continue
+			}
+			item := FeedMedia{
+				URL:    c.URL,
+				Type:   c.Type,
+				Medium: c.Medium,
+				Width:  parseInt(c.Width),
+				Height: parseInt(c.Height),
+			}
+			// A content's own thumbnail wins over its group's or item's
+			switch {
+			case len(c.Thumbnails) > 0:
+				item.Thumbnail = c.Thumbnails[0].URL
+			case len(thumbnails) > 0:
+				item.Thumbnail = thumbnails[0].URL
+			case len(m.MediaThumbnails) > 0:
+				item.Thumbnail = m.MediaThumbnails[0].URL
+			}
+			media = append(media, item)
+		}
+		// A group with only thumbnails (e.g. Flickr) still has a picture to show
+		if len(contents) == 0 && len(thumbnails) > 0 && thumbnails[0].URL != "" {
+			media = append(media, FeedMedia{
+				URL:       thumbnails[0].URL,
+				Medium:    "image",
+				Thumbnail: thumbnails[0].URL,
+				Width:     parseInt(thumbnails[0].Width),
+				Height:    parseInt(thumbnails[0].Height),
+			})
+		}
+	}
+
+	for _, group := range m.MediaGroups {
+		add(group.Contents, group.Thumbnails)
+	}
+	if len(m.MediaContents) > 0 {
+		add(m.MediaContents, nil)
+	} else if len(media) == 0 {
+		add(nil, m.MediaThumbnails)
+	}
+	return media
+}
+
+func isVideo(medium, mimeType string) bool {
+	return medium == "video" ||
+		strings.HasPrefix(mimeType, "video/") ||
+		mimeType == "application/x-shockwave-flash" // what YouTube still labels its videos
+}
+
+// describeMedia summarises what a post carries for browse, e.g. "video
+// (thumbnail: https://i.ytimg.com/vi/.../hqdefault.jpg)"
+func describeMedia(items []database.MediaItem) string {
+	kind := "image"
+	thumbnail := ""
+	for _, item := range items {
+		if isVideo(item.Medium.String, item.MimeType.String) {
+			kind = "video"
+		}
+		if thumbnail == "" && item.ThumbnailUrl.Valid {
+			thumbnail = item.ThumbnailUrl.String
+		}
+	}
+	if thumbnail == "" {
+		return kind
+	}
+	return kind + " (thumbnail: " + thumbnail + ")"
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/voidarchive/Gator/internal/database"
+)
+
+// iTunesItem holds the podcast-specific tags Apple's namespace adds to an RSS item
+type iTunesItem struct {
+	Duration string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	Episode  string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episode"`
+	Season   string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd season"`
+	Image    struct {
+		Href string `xml:"href,attr"`
+	} `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+}
+
+// parseDuration accepts the forms itunes:duration shows up in the wild:
+// plain seconds, MM:SS and HH:MM:SS
+func parseDuration(s string) int {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+	total := 0
+	for _, part := range strings.Split(s, ":") {
+		n, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || n < 0 {
+			return 0
+		}
+		total = total*60 + int(n)
+	}
+	return total
+}
+
+func parseInt(s string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+func parseLength(s string) int64 {
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// formatDuration renders seconds as H:MM:SS or M:SS
+func formatDuration(seconds int) string {
+	h, m, s := seconds/3600, seconds/60%60, seconds%60
+	if h > 0 {
+		return strconv.Itoa(h) + ":" + pad2(m) + ":" + pad2(s)
+	}
+	return strconv.Itoa(m) + ":" + pad2(s)
+}
+
+func pad2(n int) string {
+	if n < 10 {
+		return "0" + strconv.Itoa(n)
+	}
+	return strconv.Itoa(n)
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return strconv.FormatInt(n, 10) + " B"
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return strconv.FormatFloat(float64(n)/float64(div), 'f', 1, 64) + " " + string("KMGTPE"[exp]) + "B"
+}
+
+// describeEnclosure summarises an enclosure on one line, e.g.
+// "S2E5 audio/mpeg, 45:12, 49.9 MB - https://example.com/ep5.mp3"
+func describeEnclosure(enc database.Enclosure) string {
+	var parts []string
+	if enc.MimeType.Valid {
+		parts = append(parts, enc.MimeType.String)
+	}
+	if enc.DurationSeconds.Valid {
+		parts = append(parts, formatDuration(int(enc.DurationSeconds.Int32)))
+	}
+	if enc.Length.Valid {
+		parts = append(parts, formatBytes(enc.Length.Int64))
+	}
+
+	desc := strings.Join(parts, ", ")
+	switch {
+	case enc.Season.Valid && enc.Episode.Valid:
+		desc = fmt.Sprintf("S%dE%d %s", enc.Season.Int32, enc.Episode.Int32, desc)
+	case enc.Episode.Valid:
+		desc = fmt.Sprintf("Episode %d %s", enc.Episode.Int32, desc)
+	}
+	if desc == "" {
+		return enc.Url
+	}
+	return strings.TrimSpace(desc) + " - " + enc.Url
+}
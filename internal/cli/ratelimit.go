@@ -0,0 +1,186 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHostRateLimit   = 30 // requests per minute
+	defaultHostMaxInFlight = 2
+	hostBurst              = 3
+
+	// How long a host is left alone after a 429/503 that doesn't say
+	defaultRetryAfter = time.Minute
+	maxRetryAfter     = 24 * time.Hour
+)
+
+// hostDeferredError means a host asked us to back off, either just now or on
+// an earlier request, and nothing should be fetched from it before Until
+type hostDeferredError struct {
+	Host  string
+	Until time.Time
+	Err   error
+}
+
+func (e *hostDeferredError) Error() string {
+	msg := fmt.Sprintf("%s asked us to back off until %s", e.Host, e.Until.Format(time.RFC3339))
+	if e.Err != nil {
+		msg += fmt.Sprintf(" (%v)", e.Err)
+	}
+	return msg
+}
+
+// hostBusyError means a host already has as many requests as it's allowed,
+// so the feed should be tried again after Wait rather than waited on
+type hostBusyError struct {
+	Host string
+	Wait time.Duration
+}
+
+func (e *hostBusyError) Error() string {
+	return fmt.Sprintf("%s is busy, try again in %v", e.Host, e.Wait.Round(time.Second))
+}
+
+// parseRetryAfter reads a Retry-After header, which is either a number of
+// seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// hostLimiter gives each host a token bucket, refilled at a fixed number of
+// requests per minute, and caps how many requests to it run at once
+type hostLimiter struct {
+	mu          sync.Mutex
+	rate        float64 // tokens per second
+	maxInFlight int
+	hosts       map[string]*hostState
+}
+
+type hostState struct {
+	slots        chan struct{} // one per request in flight
+	tokens       float64
+	lastRefill   time.Time
+	blockedUntil time.Time
+	nextTurn     time.Time // the latest turn handed out to a feed turned away
+}
+
+func newHostLimiter(requestsPerMinute, maxInFlight int) *hostLimiter {
+	return &hostLimiter{
+		rate:        float64(requestsPerMinute) / 60,
+		maxInFlight: maxInFlight,
+		hosts:       make(map[string]*hostState),
+	}
+}
+
+func (l *hostLimiter) state(host string) *hostState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h, ok := l.hosts[host]
+	if !ok {
+		h = &hostState{
+			slots:      make(chan struct{}, l.maxInFlight),
+			tokens:     hostBurst,
+			lastRefill: time.Now(),
+		}
+		l.hosts[host] = h
+	}
+	return h
+}
+
+// acquire takes a free slot and a token for host. It never waits for them:
+// when the host is busy it fails with a hostBusyError, so the caller can move
+// on to other hosts' feeds, and when the host has asked us to back off it
+// fails with a hostDeferredError.
+func (l *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	h := l.state(host)
+	if err := l.checkDeferred(host, h); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	select {
+	case h.slots <- struct{}{}:
+	default:
+		return nil, &hostBusyError{Host: host, Wait: l.nextTurn(h)}
+	}
+	release := func() { <-h.slots }
+
+	wait, err := l.takeToken(host, h)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	if wait > 0 {
+		release()
+		return nil, &hostBusyError{Host: host, Wait: l.nextTurn(h)}
+	}
+	return release, nil
+}
+
+// nextTurn spreads out the feeds turned away from a busy host by giving each
+// a later turn than the last, at the rate the host allows
+func (l *hostLimiter) nextTurn(h *hostState) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if h.nextTurn.Before(now) {
+		h.nextTurn = now
+	}
+	h.nextTurn = h.nextTurn.Add(time.Duration(float64(time.Second) / l.rate))
+	return h.nextTurn.Sub(now)
+}
+
+func (l *hostLimiter) checkDeferred(host string, h *hostState) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if time.Now().Before(h.blockedUntil) {
+		return &hostDeferredError{Host: host, Until: h.blockedUntil}
+	}
+	return nil
+}
+
+// takeToken returns how long to wait before trying again, or zero once a
+// token has been taken
+func (l *hostLimiter) takeToken(host string, h *hostState) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(h.blockedUntil) {
+		return 0, &hostDeferredError{Host: host, Until: h.blockedUntil}
+	}
+	h.tokens = min(hostBurst, h.tokens+now.Sub(h.lastRefill).Seconds()*l.rate)
+	h.lastRefill = now
+	if h.tokens < 1 {
+		return max(time.Duration((1-h.tokens)/l.rate*float64(time.Second)), time.Millisecond), nil
+	}
+	h.tokens--
+	return 0, nil
+}
+
+func (l *hostLimiter) deferHost(host string, until time.Time) {
+	h := l.state(host)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until.After(h.blockedUntil) {
+		h.blockedUntil = until
+	}
+}
@@ -0,0 +1,110 @@
+package cli
+
+import "encoding/xml"
+
+const (
+	rdfNamespace   = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	rss10Namespace = "http://purl.org/rss/1.0/"
+	rss09Namespace = "http://my.netscape.com/rdf/simple/0.9/"
+)
+
+// RDFFeed is the metadata of an RSS 1.0 document, where items are siblings
+// of the channel rather than children of it and metadata comes from Dublin
+// Core elements
+type RDFFeed struct {
+	Channel struct {
+		Title       rdfText `xml:"title"`
+		Link        rdfText `xml:"link"`
+		Description rdfText `xml:"description"`
+		Date        string  `xml:"http://purl.org/dc/elements/1.1/ date"`
+		Language    string  `xml:"http://purl.org/dc/elements/1.1/ language"`
+	} `xml:"channel"`
+	Image struct {
+		URL string `xml:"url"`
+	} `xml:"image"`
+}
+
+type RDFItem struct {
+	About       string   `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Title       rdfText  `xml:"title"`
+	Link        rdfText  `xml:"link"`
+	Description rdfText  `xml:"description"`
+	Content     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Date        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Creator     []string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Subject     []string `xml:"http://purl.org/dc/elements/1.1/ subject"`
+}
+
+// rdfText is the text of an RSS 1.0 element, or of its RSS 0.9 or
+// unqualified equivalent. Dublin Core reuses the same names (dc:title,
+// dc:description), which would otherwise be matched too.
+type rdfText string
+
+func (t *rdfText) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	switch start.Name.Space {
+	case rss10Namespace, rss09Namespace, "":
+	default:
+		return d.Skip()
+	}
+	var text string
+	if err := d.DecodeElement(&text, &start); err != nil {
+		return err
+	}
+	*t = rdfText(text)
+	return nil
+}
+
+// rdfReader reads an RSS 1.0 document, decoding its items one at a time
+type rdfReader struct {
+	decoder *xml.Decoder
+	feed    RDFFeed
+}
+
+func (r *rdfReader) header() *ParsedFeed {
+	return &ParsedFeed{
+		Title:       string(r.feed.Channel.Title),
+		Link:        string(r.feed.Channel.Link),
+		Description: string(r.feed.Channel.Description),
+		Language:    r.feed.Channel.Language,
+		Image:       r.feed.Image.URL,
+		Updated:     r.feed.Channel.Date,
+	}
+}
+
+func (r *rdfReader) next() (*FeedItem, error) {
+	return nextXMLItem(r.decoder, func(se xml.StartElement) (*FeedItem, error) {
+		switch se.Name.Local {
+		case "item":
+			var item RDFItem
+			if err := r.decoder.DecodeElement(&item, &se); err != nil {
+				return nil, err
+			}
+			feedItem := item.toFeedItem()
+			return &feedItem, nil
+		case "channel":
+			return nil, r.decoder.DecodeElement(&r.feed.Channel, &se)
+		case "image":
+			return nil, r.decoder.DecodeElement(&r.feed.Image, &se)
+		default:
+			return nil, r.decoder.Skip()
+		}
+	})
+}
+
+func (item *RDFItem) toFeedItem() FeedItem {
+	var authors []FeedAuthor
+	for _, creator := range item.Creator {
+		authors = append(authors, FeedAuthor{Name: creator})
+	}
+
+	return FeedItem{
+		GUID:        item.About,
+		Title:       string(item.Title),
+		Link:        string(item.Link),
+		Description: string(item.Description),
+		Content:     item.Content,
+		PubDate:     item.Date,
+		Authors:     authors,
+		Categories:  item.Subject,
+	}
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRDFReader(t *testing.T) {
+	tests := []struct {
+		name      string
+		doc       string
+		wantFeed  ParsedFeed
+		wantItems []FeedItem
+	}{
+		{
+			name: "RSS 1.0",
+			doc: `<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/"
+	xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+	<channel rdf:about="https://example.org/rss">
+		<title>Tom &amp; Jerry</title>
+		<link>https://example.org/</link>
+		<description>A blog</description>
+		<dc:title>DC title</dc:title>
+		<dc:description>DC description</dc:description>
+		<dc:language>en</dc:language>
+		<dc:date>2024-05-01T10:00:00Z</dc:date>
+		<items><rdf:Seq><rdf:li resource="https://example.org/1"/></rdf:Seq></items>
+	</channel>
+	<image rdf:about="https://example.org/logo.png"><url>/logo.png</url></image>
+	<item rdf:about="https://example.org/1">
+		<title>First &amp;amp; best</title>
+		<link>posts/1</link>
+		<description>The first</description>
+		<dc:title>DC item title</dc:title>
+		<dc:description>DC item description</dc:description>
+		<content:encoded><![CDATA[<img src="/1.jpg">]]></content:encoded>
+		<dc:date>2024-05-01T09:00:00Z</dc:date>
+		<dc:creator>Tom</dc:creator>
+		<dc:creator>Jerry</dc:creator>
+		<dc:subject>news</dc:subject>
+	</item>
+	<item rdf:about="https://example.org/2">
+		<title>Second</title>
+		<link>https://example.org/2</link>
+	</item>
+</rdf:RDF>`,
+			wantFeed: ParsedFeed{
+				Title:       "Tom & Jerry",
+				Link:        "https://example.org/",
+				Description: "A blog",
+				Language:    "en",
+				Image:       "https://example.org/logo.png",
+				Updated:     "2024-05-01T10:00:00Z",
+				Encoding:    "utf-8",
+			},
+			wantItems: []FeedItem{
+				{
+					GUID:        "https://example.org/1",
+					Title:       "First & best",
+					Link:        "https://example.org/posts/1",
+					Description: "The first",
+					Content:     `<img src="https://example.org/1.jpg">`,
+					PubDate:     "2024-05-01T09:00:00Z",
+					Authors:     []FeedAuthor{{Name: "Tom"}, {Name: "Jerry"}},
+					Categories:  []string{"news"},
+				},
+				{GUID: "https://example.org/2", Title: "Second", Link: "https://example.org/2"},
+			},
+		},
+		{
+			name: "RSS 0.90",
+			doc: `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://my.netscape.com/rdf/simple/0.9/">
+				<channel><title>Old</title><link>https://example.org/</link><description>Very old</description></channel>
+				<item><title>One</title><link>https://example.org/1</link></item>
+			</rdf:RDF>`,
+			wantFeed:  ParsedFeed{Title: "Old", Link: "https://example.org/", Description: "Very old", Encoding: "utf-8"},
+			wantItems: []FeedItem{{Title: "One", Link: "https://example.org/1"}},
+		},
+		{
+			name: "no default namespace",
+			doc: `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+				<channel><title>Sloppy</title></channel>
+				<item><title>One</title><link>https://example.org/1</link></item>
+			</rdf:RDF>`,
+			wantFeed:  ParsedFeed{Title: "Sloppy", Encoding: "utf-8"},
+			wantItems: []FeedItem{{Title: "One", Link: "https://example.org/1"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, items, err := readFeed(t, tt.doc, "application/rdf+xml")
+			if err != nil {
+				t.Fatalf("reading feed: %v", err)
+			}
+			if !reflect.DeepEqual(*feed, tt.wantFeed) {
+				t.Errorf("feed = %+v, want %+v", *feed, tt.wantFeed)
+			}
+			if len(items) != len(tt.wantItems) {
+				t.Fatalf("got %d items, want %d", len(items), len(tt.wantItems))
+			}
+			for i := range items {
+				if !reflect.DeepEqual(items[i], tt.wantItems[i]) {
+					t.Errorf("item %d = %+v, want %+v", i, items[i], tt.wantItems[i])
+				}
+			}
+		})
+	}
+}
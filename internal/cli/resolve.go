@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var htmlURLAttrPattern = regexp.MustCompile(`(?i)(\s(?:href|src|poster)\s*=\s*)("[^"]*"|'[^']*')`)
+
+// resolveURLs makes the feed's own links absolute and returns the base its
+// items' relative references are resolved against: xml:base where the feed
+// sets it, then the channel link, and finally the URL the feed was fetched from.
+func (f *ParsedFeed) resolveURLs(feedURL string) *url.URL {
+	docBase, err := url.Parse(feedURL)
+	if err != nil {
+		return nil
+	}
+
+	feedBase := docBase
+	if f.Base != "" {
+		feedBase = resolveBase(docBase, f.Base)
+	}
+	f.Link = resolveURL(feedBase, f.Link)
+
+	// Without an explicit xml:base, relative item links are far more often
+	// relative to the site than to wherever the feed happens to be served from
+	siteBase := feedBase
+	if f.Base == "" && f.Link != "" {
+		siteBase = resolveBase(feedBase, f.Link)
+	}
+	f.Image = resolveURL(siteBase, f.Image)
+	return siteBase
+}
+
+// resolveURLs makes every link in the item absolute, honouring the item's
+// own xml:base
+func (item *FeedItem) resolveURLs(siteBase *url.URL) {
+	if siteBase == nil {
+		return
+	}
+	base := siteBase
+	if item.Base != "" {
+		base = resolveBase(siteBase, item.Base)
+	}
+
+	item.Link = resolveURL(base, item.Link)
+	item.Description = resolveHTMLURLs(base, item.Description)
+	item.Content = resolveHTMLURLs(base, item.Content)
+	for j := range item.Enclosures {
+		item.Enclosures[j].URL = resolveURL(base, item.Enclosures[j].URL)
+		item.Enclosures[j].Image = resolveURL(base, item.Enclosures[j].Image)
+	}
+	for j := range item.Media {
+		item.Media[j].URL = resolveURL(base, item.Media[j].URL)
+		item.Media[j].Thumbnail = resolveURL(base, item.Media[j].Thumbnail)
+	}
+}
+
+func resolveBase(base *url.URL, ref string) *url.URL {
+	u, err := base.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return base
+	}
+	return u
+}
+
+func resolveURL(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil || u.IsAbs() {
+		return ref
+	}
+	return base.ResolveReference(u).String()
+}
+
+// resolveHTMLURLs rewrites relative href/src attributes in an HTML fragment,
+// leaving fragments-only links and non-HTTP schemes alone
+func resolveHTMLURLs(base *url.URL, fragment string) string {
+	if fragment == "" {
+		return fragment
+	}
+	return htmlURLAttrPattern.ReplaceAllStringFunc(fragment, func(attr string) string {
+		m := htmlURLAttrPattern.FindStringSubmatch(attr)
+		quote, value := m[2][:1], m[2][1:len(m[2])-1]
+		if value == "" || strings.HasPrefix(value, "#") {
+			return attr
+		}
+		return m[1] + quote + resolveURL(base, value) + quote
+	})
+}
@@ -1,65 +1,210 @@
 package cli
 
 import (
-	"context"
 	"encoding/xml"
-	"fmt"
-	"html"
-	"io"
-	"net/http"
+	"strings"
 )
 
-type RSSFeed struct {
-	Channel struct {
-		Title       string    `xml:"title"`
-		Link        string    `xml:"link"`
-		Description string    `xml:"description"`
-		Item        []RSSItem `xml:"item"`
-	} `xml:"channel"`
+// RSSChannel is the metadata of an RSS 2.0 channel, which comes before its
+// items in nearly every feed
+type RSSChannel struct {
+	Base          string
+	Title         string
+	Link          string
+	Description   string
+	Language      string
+	Generator     string
+	Image         string
+	LastBuildDate string
+	PubDate       string
 }
 
 type RSSItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+	Base string `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	GUID struct {
+		Value       string `xml:",chardata"`
+		IsPermaLink string `xml:"isPermaLink,attr"`
+	} `xml:"guid"`
+	Title       rssText   `xml:"title"`
+	Link        rssText   `xml:"link"`
+	Description rssText   `xml:"description"`
+	Content     string    `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PubDate     rssText   `xml:"pubDate"`
+	Author      rssText   `xml:"author"`
+	Creator     []string  `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Category    []rssText `xml:"category"`
+	Enclosures  []struct {
+		URL    string `xml:"url,attr"`
+		Length string `xml:"length,attr"`
+		Type   string `xml:"type,attr"`
+	} `xml:"enclosure"`
+	iTunesItem
+	mediaRSS
 }
 
-func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %v", err)
+// rssText is the text of an RSS element. A tag without a namespace matches
+// the element's name in any namespace, so extensions sharing a name with it
+// (media:title, atom:link, itunes:author) are skipped here, as they are for
+// the channel.
+type rssText string
+
+func (t *rssText) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	if start.Name.Space != "" {
+		return d.Skip()
+	}
+	var text string
+	if err := d.DecodeElement(&text, &start); err != nil {
+		return err
 	}
-	req.Header.Set("User-Agent", "gator")
+	*t = rssText(text)
+	return nil
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error making request: %v", err)
+// field is where the text of a channel element goes, or nil for elements
+// that aren't kept
+func (c *RSSChannel) field(name string) *string {
+	switch name {
+	case "title":
+		return &c.Title
+	case "link":
+		return &c.Link
+	case "description":
+		return &c.Description
+	case "language":
+		return &c.Language
+	case "generator":
+		return &c.Generator
+	case "lastBuildDate":
+		return &c.LastBuildDate
+	case "pubDate":
+		return &c.PubDate
 	}
-	defer resp.Body.Close()
+	return nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+// rssReader reads an RSS 2.0 document, descending into the channel and
+// decoding its items one at a time
+type rssReader struct {
+	decoder *xml.Decoder
+	channel RSSChannel
+}
+
+func (r *rssReader) header() *ParsedFeed {
+	feed := &ParsedFeed{
+		Title:       r.channel.Title,
+		Link:        r.channel.Link,
+		Description: r.channel.Description,
+		Language:    strings.TrimSpace(r.channel.Language),
+		Image:       strings.TrimSpace(r.channel.Image),
+		Generator:   strings.TrimSpace(r.channel.Generator),
+		Base:        r.channel.Base,
+		Updated:     r.channel.LastBuildDate,
+	}
+	if feed.Updated == "" {
+		feed.Updated = r.channel.PubDate
 	}
+	return feed
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading response body: %v", err)
+func (r *rssReader) next() (*FeedItem, error) {
+	return nextXMLItem(r.decoder, func(se xml.StartElement) (*FeedItem, error) {
+		field := r.channel.field(se.Name.Local)
+		switch {
+		case se.Name.Local == "channel":
+			r.channel.Base = xmlAttr(se, xmlNamespace, "base")
+			return nil, nil
+		case se.Name.Local == "item":
+			var item RSSItem
+			if err := r.decoder.DecodeElement(&item, &se); err != nil {
+				return nil, err
+			}
+			feedItem := item.toFeedItem()
+			return &feedItem, nil
+		case se.Name.Space == "" && se.Name.Local == "image":
+			var image struct {
+				URL string `xml:"url"`
+			}
+			if err := r.decoder.DecodeElement(&image, &se); err != nil {
+				return nil, err
+			}
+			r.channel.Image = image.URL
+			return nil, nil
+		case se.Name.Space == "" && field != nil:
+			// Only unprefixed elements, so extensions like atom:link are left alone
+			return nil, r.decoder.DecodeElement(field, &se)
+		default:
+			return nil, r.decoder.Skip()
+		}
+	})
+}
+
+func (item *RSSItem) toFeedItem() FeedItem {
+	var enclosures []FeedEnclosure
+	for _, enc := range item.Enclosures {
+		if enc.URL == "" {
+			continue
+		}
+		enclosures = append(enclosures, FeedEnclosure{
+			URL:      enc.URL,
+			Type:     enc.Type,
+			Length:   parseLength(enc.Length),
+			Duration: parseDuration(item.Duration),
+			Episode:  parseInt(item.Episode),
+			Season:   parseInt(item.Season),
+			Image:    item.Image.Href,
+		})
+	}
+
+	guid := strings.TrimSpace(item.GUID.Value)
+	link := strings.TrimSpace(string(item.Link))
+	if link == "" && guid != "" && item.GUID.IsPermaLink != "false" {
+		// A permalink GUID doubles as the item's link
+		link = guid
 	}
-	var feed RSSFeed
 
-	if err := xml.Unmarshal(body, &feed); err != nil {
-		return nil, fmt.Errorf("error unmarshaling XML: %v", err)
+	var authors []FeedAuthor
+	if item.Author != "" {
+		authors = append(authors, parseRSSAuthor(string(item.Author)))
+	}
+	for _, creator := range item.Creator {
+		authors = append(authors, FeedAuthor{Name: creator})
 	}
 
-	feed.Channel.Title = html.UnescapeString(feed.Channel.Title)
-	feed.Channel.Description = html.UnescapeString(feed.Channel.Description)
+	var categories []string
+	for _, category := range item.Category {
+		// Skipped media:category elements leave an empty entry behind
+		if category != "" {
+			categories = append(categories, string(category))
+		}
+	}
 
-	for i := range feed.Channel.Item {
-		feed.Channel.Item[i].Title = html.UnescapeString(feed.Channel.Item[i].Title)
-		feed.Channel.Item[i].Description = html.UnescapeString(feed.Channel.Item[i].Description)
+	return FeedItem{
+		GUID:        guid,
+		Base:        item.Base,
+		Title:       string(item.Title),
+		Link:        link,
+		Description: string(item.Description),
+		Content:     item.Content,
+		PubDate:     string(item.PubDate),
+		Authors:     authors,
+		Categories:  categories,
+		Enclosures:  enclosures,
+		Media:       item.toFeedMedia(),
 	}
+}
 
-	return &feed, nil
+// parseRSSAuthor splits the RSS 2.0 "email (Name)" author convention; plenty
+// of feeds put just a name there, which is kept as-is
+func parseRSSAuthor(s string) FeedAuthor {
+	s = strings.TrimSpace(s)
+	if open := strings.Index(s, "("); open > 0 && strings.HasSuffix(s, ")") {
+		email := strings.TrimSpace(s[:open])
+		if strings.Contains(email, "@") {
+			return FeedAuthor{Name: strings.TrimSpace(s[open+1 : len(s)-1]), Email: email}
+		}
+	}
+	if strings.Contains(s, "@") && !strings.Contains(s, " ") {
+		return FeedAuthor{Name: s, Email: s}
+	}
+	return FeedAuthor{Name: s}
 }
@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRSSReader(t *testing.T) {
+	tests := []struct {
+		name      string
+		doc       string
+		wantFeed  ParsedFeed
+		wantItems []FeedItem
+	}{
+		{
+			name: "RSS 2.0",
+			doc: `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/"
+	xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:atom="http://www.w3.org/2005/Atom"
+	xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd" xmlns:media="http://search.yahoo.com/mrss/">
+<channel>
+	<title>Tom &amp; Jerry</title>
+	<link>https://example.org/blog/</link>
+	<atom:link href="https://example.org/feed.xml" rel="self" type="application/rss+xml"/>
+	<description>A &lt;b&gt;blog&lt;/b&gt;</description>
+	<language> en-gb </language>
+	<generator>Hugo</generator>
+	<image><url>/logo.png</url><title>Logo</title></image>
+	<lastBuildDate>Wed, 01 May 2024 10:00:00 GMT</lastBuildDate>
+	<pubDate>Tue, 30 Apr 2024 10:00:00 GMT</pubDate>
+	<item>
+		<title>Episode &amp;#8220;1&amp;#8221;</title>
+		<link> posts/1 </link>
+		<guid isPermaLink="false"> tag:example.org,2024:1 </guid>
+		<description><![CDATA[<p>See <a href="/about">about</a></p>]]></description>
+		<content:encoded><![CDATA[<img src="img/1.jpg">]]></content:encoded>
+		<pubDate>Wed, 01 May 2024 09:00:00 GMT</pubDate>
+		<author>tom@example.org (Tom)</author>
+		<dc:creator>Jerry</dc:creator>
+		<category>news</category>
+		<category>go</category>
+		<enclosure url="ep1.mp3" length="1234" type="audio/mpeg"/>
+		<enclosure length="1"/>
+		<itunes:duration>1:01:01</itunes:duration>
+		<itunes:episode>3</itunes:episode>
+		<itunes:season>2</itunes:season>
+		<itunes:image href="https://cdn.example.org/ep1.jpg"/>
+		<media:content url="https://cdn.example.org/v.mp4" type="video/mp4" medium="video" width="640" height="360">
+			<media:thumbnail url="https://cdn.example.org/v.jpg"/>
+		</media:content>
+	</item>
+	<item>
+		<guid>https://example.org/blog/posts/2</guid>
+		<title>Second</title>
+		<author>jerry@example.org</author>
+	</item>
+	<item>
+		<guid isPermaLink="false">3</guid>
+		<title>Third</title>
+	</item>
+</channel>
+</rss>`,
+			wantFeed: ParsedFeed{
+				Title:       "Tom & Jerry",
+				Link:        "https://example.org/blog/",
+				Description: "A <b>blog</b>",
+				Language:    "en-gb",
+				Image:       "https://example.org/logo.png",
+				Generator:   "Hugo",
+				Updated:     "Wed, 01 May 2024 10:00:00 GMT",
+				Encoding:    "utf-8",
+			},
+			wantItems: []FeedItem{
+				{
+					GUID:        "tag:example.org,2024:1",
+					Title:       "Episode “1”",
+					Link:        "https://example.org/blog/posts/1",
+					Description: `<p>See <a href="https://example.org/about">about</a></p>`,
+					Content:     `<img src="https://example.org/blog/img/1.jpg">`,
+					PubDate:     "Wed, 01 May 2024 09:00:00 GMT",
+					Authors:     []FeedAuthor{{Name: "Tom", Email: "tom@example.org"}, {Name: "Jerry"}},
+					Categories:  []string{"news", "go"},
+					Enclosures: []FeedEnclosure{{
+						URL:      "https://example.org/blog/ep1.mp3",
+						Type:     "audio/mpeg",
+						Length:   1234,
+						Duration: 3661,
+						Episode:  3,
+						Season:   2,
+						Image:    "https://cdn.example.org/ep1.jpg",
+					}},
+					Media: []FeedMedia{{
+						URL:       "https://cdn.example.org/v.mp4",
+						Type:      "video/mp4",
+						Medium:    "video",
+						Thumbnail: "https://cdn.example.org/v.jpg",
+						Width:     640,
+						Height:    360,
+					}},
+				},
+				{
+					GUID:    "https://example.org/blog/posts/2",
+					Title:   "Second",
+					Link:    "https://example.org/blog/posts/2",
+					Authors: []FeedAuthor{{Name: "jerry@example.org", Email: "jerry@example.org"}},
+				},
+				{GUID: "3", Title: "Third"},
+			},
+		},
+		{
+			name: "pubDate stands in for lastBuildDate",
+			doc: `<rss version="2.0"><channel><title>T</title><pubDate>Tue, 30 Apr 2024 10:00:00 GMT</pubDate>
+				<item><title>One</title><link>https://example.org/1</link></item></channel></rss>`,
+			wantFeed:  ParsedFeed{Title: "T", Updated: "Tue, 30 Apr 2024 10:00:00 GMT", Encoding: "utf-8"},
+			wantItems: []FeedItem{{Title: "One", Link: "https://example.org/1"}},
+		},
+		{
+			name: "xml:base",
+			doc: `<rss version="2.0"><channel xml:base="https://cdn.example.org/a/"><title>T</title><link>/home</link>
+				<item xml:base="b/"><link>1.html</link></item></channel></rss>`,
+			wantFeed: ParsedFeed{
+				Title:    "T",
+				Link:     "https://cdn.example.org/home",
+				Base:     "https://cdn.example.org/a/",
+				Encoding: "utf-8",
+			},
+			wantItems: []FeedItem{{Base: "b/", Link: "https://cdn.example.org/a/b/1.html"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, items, err := readFeed(t, tt.doc, "application/rss+xml")
+			if err != nil {
+				t.Fatalf("reading feed: %v", err)
+			}
+			if !reflect.DeepEqual(*feed, tt.wantFeed) {
+				t.Errorf("feed = %+v, want %+v", *feed, tt.wantFeed)
+			}
+			if len(items) != len(tt.wantItems) {
+				t.Fatalf("got %d items, want %d", len(items), len(tt.wantItems))
+			}
+			for i := range items {
+				if !reflect.DeepEqual(items[i], tt.wantItems[i]) {
+					t.Errorf("item %d = %+v, want %+v", i, items[i], tt.wantItems[i])
+				}
+			}
+		})
+	}
+}
+
+// TestRSSItemNamespaces checks that extension elements sharing a name with
+// an RSS item element don't take its place, whichever comes first
+func TestRSSItemNamespaces(t *testing.T) {
+	const namespaces = `xmlns:media="http://search.yahoo.com/mrss/" xmlns:atom="http://www.w3.org/2005/Atom" ` +
+		`xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd"`
+	plain := FeedItem{
+		Title:       "Plain title",
+		Link:        "https://example.org/1",
+		Description: "Plain description",
+		Authors:     []FeedAuthor{{Name: "Plain author"}},
+		Categories:  []string{"plain"},
+	}
+	elements := `<title>Plain title</title><link>https://example.org/1</link>` +
+		`<description>Plain description</description><author>Plain author</author><category>plain</category>`
+
+	tests := []struct {
+		name, extension string
+	}{
+		{"media:title", `<media:title>Media title</media:title>`},
+		{"media:description", `<media:description>Media description</media:description>`},
+		{"atom:link", `<atom:link rel="related" href="https://example.org/related"/>`},
+		{"itunes:title", `<itunes:title>Episode title</itunes:title>`},
+		{"itunes:author", `<itunes:author>Podcast author</itunes:author>`},
+		{"media:category", `<media:category>Media category</media:category>`},
+		{"undeclared prefix", `<media:title>Media title</media:title><foo:link>x</foo:link>`},
+	}
+	for _, tt := range tests {
+		for _, order := range []struct{ name, item string }{
+			{"after", elements + tt.extension},
+			{"before", tt.extension + elements},
+		} {
+			t.Run(tt.name+" "+order.name, func(t *testing.T) {
+				doc := `<rss version="2.0" ` + namespaces + `><channel><item>` + order.item + `</item></channel></rss>`
+				_, items, err := readFeed(t, doc, "application/rss+xml")
+				if err != nil {
+					t.Fatalf("reading feed: %v", err)
+				}
+				if len(items) != 1 {
+					t.Fatalf("got %d items, want 1", len(items))
+				}
+				if !reflect.DeepEqual(items[0], plain) {
+					t.Errorf("item = %+v, want %+v", items[0], plain)
+				}
+			})
+		}
+	}
+}
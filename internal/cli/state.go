@@ -1,11 +1,14 @@
 package cli
 
 import (
+	"database/sql"
+
 	"github.com/voidarchive/Gator/internal/config"
 	"github.com/voidarchive/Gator/internal/database"
 )
 
 type State struct {
-	Cfg *config.Config
-	DB  *database.Queries
+	Cfg  *config.Config
+	DB   *database.Queries
+	Conn *sql.DB // for queries that have to run in one transaction
 }
@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	blockTagPattern  = regexp.MustCompile(`(?i)<\s*(br|/p|/div|/li|/h[1-6]|/blockquote|/pre|/tr)\s*/?>`)
+	anyTagPattern    = regexp.MustCompile(`(?s)<[^>]*>`)
+	scriptPattern    = regexp.MustCompile(`(?is)<(script|style)[^>]*>.*?</(script|style)\s*>`)
+	spacesPattern    = regexp.MustCompile(`[ \t\f\r]+`)
+	blankLinePattern = regexp.MustCompile(`\n\s*\n\s*(\n\s*)+`)
+)
+
+// htmlToText renders article HTML as plain text good enough for a terminal:
+// block elements become line breaks, everything else is stripped
+func htmlToText(s string) string {
+	s = scriptPattern.ReplaceAllString(s, "")
+	s = blockTagPattern.ReplaceAllString(s, "\n")
+	s = anyTagPattern.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	s = strings.ReplaceAll(s, "\u00a0", " ")
+	s = spacesPattern.ReplaceAllString(s, " ")
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	s = strings.Join(lines, "\n")
+	s = blankLinePattern.ReplaceAllString(s, "\n\n")
+	return strings.TrimSpace(s)
+}
+
+func truncateText(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:max])) + "..."
+}
@@ -1,16 +1,39 @@
 package config
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 )
 
-const configFileName = ".gatorconfig.json"
+const (
+	configFileName          = ".gatorconfig.json"
+	defaultDownloadDir      = "gator-downloads"
+	defaultFailureThreshold = 10
+)
 
 type Config struct {
-	DbURL           string `json:"db_url"`
-	CurrentUserName string `json:"current_user_name"`
+	DbURL                string `json:"db_url"`
+	CurrentUserName      string `json:"current_user_name"`
+	DownloadDir          string `json:"download_dir,omitempty"`
+	AggWorkers           int    `json:"agg_workers,omitempty"`
+	AggBatchSize         int    `json:"agg_batch_size,omitempty"`
+	HostRateLimit        int    `json:"host_requests_per_minute,omitempty"`
+	HostMaxInFlight      int    `json:"host_max_in_flight,omitempty"`
+	DisableAfterFailures int    `json:"disable_after_failures,omitempty"`
+	ConnectTimeout       string `json:"connect_timeout,omitempty"`
+	ReadTimeout          string `json:"read_timeout,omitempty"`
+	FetchTimeout         string `json:"fetch_timeout,omitempty"`
+	MaxFeedSizeMB        int    `json:"max_feed_size_mb,omitempty"`
+	UserAgent            string `json:"user_agent,omitempty"`
+	Proxy                string `json:"proxy,omitempty"`
+	CAFile               string `json:"ca_file,omitempty"`
+	ClientCertFile       string `json:"client_cert_file,omitempty"`
+	ClientKeyFile        string `json:"client_key_file,omitempty"`
+	SecretKey            string `json:"secret_key,omitempty"`
 }
 
 func getConfigFilePath() (string, error) {
@@ -38,6 +61,59 @@ func Read() (Config, error) {
 	return cfg, nil
 }
 
+// DownloadPath returns where podcast episodes are saved, defaulting to
+// ~/gator-downloads when download_dir isn't set
+func (cfg *Config) DownloadPath() (string, error) {
+	if cfg.DownloadDir != "" {
+		return cfg.DownloadDir, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, defaultDownloadDir), nil
+}
+
+// FailureThreshold is how many failed fetches in a row disable a feed
+func (cfg *Config) FailureThreshold() int {
+	if cfg.DisableAfterFailures > 0 {
+		return cfg.DisableAfterFailures
+	}
+	return defaultFailureThreshold
+}
+
+// CredentialsKey returns the key feed credentials are encrypted with in the
+// database. Without it the stored credentials can't be read and have to be
+// set again.
+func (cfg *Config) CredentialsKey() ([]byte, error) {
+	if cfg.SecretKey == "" {
+		return nil, fmt.Errorf("secret_key is missing from the config, so stored fetch settings can't be read")
+	}
+	key, err := base64.StdEncoding.DecodeString(cfg.SecretKey)
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("secret_key must be 32 bytes, base64 encoded")
+	}
+	return key, nil
+}
+
+// EnsureCredentialsKey is CredentialsKey for storing credentials, generating
+// and saving a key the first time one is needed
+func (cfg *Config) EnsureCredentialsKey() ([]byte, error) {
+	if cfg.SecretKey != "" {
+		return cfg.CredentialsKey()
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	cfg.SecretKey = base64.StdEncoding.EncodeToString(key)
+	if err := write(*cfg); err != nil {
+		cfg.SecretKey = ""
+		return nil, err
+	}
+	return key, nil
+}
+
 func (cfg *Config) SetUser(username string) error {
 	cfg.CurrentUserName = username
 	return write(*cfg)
@@ -53,5 +129,10 @@ func write(cfg Config) error {
 		return err
 	}
 
-	return os.WriteFile(configPath, data, 0644)
+	// The file holds secret_key, so only its owner may read it. WriteFile only
+	// sets the mode of new files, which is why an existing one is chmodded.
+	if err := os.WriteFile(configPath, data, 0600); err != nil {
+		return err
+	}
+	return os.Chmod(configPath, 0600)
 }
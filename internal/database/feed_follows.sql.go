@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -64,19 +65,17 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 }
 
 const deleteFeedFollow = `-- name: DeleteFeedFollow :exec
-DELETE FROM feed_follows 
-WHERE feed_follows.user_id = $1 AND feed_follows.feed_id = (
-    SELECT id FROM feeds WHERE url = $2
-)
+DELETE FROM feed_follows
+WHERE user_id = $1 AND feed_id = $2
 `
 
 type DeleteFeedFollowParams struct {
 	UserID uuid.UUID
-	Url    string
+	FeedID uuid.UUID
 }
 
 func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowParams) error {
-	_, err := q.db.ExecContext(ctx, deleteFeedFollow, arg.UserID, arg.Url)
+	_, err := q.db.ExecContext(ctx, deleteFeedFollow, arg.UserID, arg.FeedID)
 	return err
 }
 
@@ -84,6 +83,11 @@ const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
 SELECT
     ff.id, ff.created_at, ff.updated_at, ff.user_id, ff.feed_id,
     feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    feeds.site_url,
+    feeds.description,
+    feeds.language,
+    feeds.last_build_date,
     users.name AS user_name
 FROM feed_follows ff
 JOIN feeds  ON ff.feed_id   = feeds.id
@@ -92,13 +96,18 @@ WHERE ff.user_id = $1
 `
 
 type GetFeedFollowsForUserRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
-	FeedID    uuid.UUID
-	FeedName  string
-	UserName  string
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	UserID        uuid.UUID
+	FeedID        uuid.UUID
+	FeedName      string
+	FeedUrl       string
+	SiteUrl       sql.NullString
+	Description   sql.NullString
+	Language      sql.NullString
+	LastBuildDate sql.NullTime
+	UserName      string
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
@@ -117,6 +126,11 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 			&i.UserID,
 			&i.FeedID,
 			&i.FeedName,
+			&i.FeedUrl,
+			&i.SiteUrl,
+			&i.Description,
+			&i.Language,
+			&i.LastBuildDate,
 			&i.UserName,
 		); err != nil {
 			return nil, err
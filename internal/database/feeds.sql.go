@@ -7,11 +7,85 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const claimFeedsToFetch = `-- name: ClaimFeedsToFetch :many
+UPDATE feeds
+SET last_fetched_at = NOW() - make_interval(secs => $1), next_fetch_at = NULL, updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM feeds
+    WHERE NOT disabled AND retired_at IS NULL
+      AND ((next_fetch_at IS NULL AND (last_fetched_at IS NULL OR last_fetched_at < NOW() - make_interval(secs => $2)))
+        OR next_fetch_at <= NOW())
+    ORDER BY last_fetched_at ASC NULLS FIRST
+    LIMIT $3
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, keep_episodes, encoding, site_url, description, language, image_url, generator, last_build_date, etag, last_modified, next_fetch_at, consecutive_failures, last_success_at, last_error, disabled, retired_at
+`
+
+type ClaimFeedsToFetchParams struct {
+	PassAgeSecs  float64
+	IntervalSecs float64
+	BatchSize    int32
+}
+
+// Marks a batch of due feeds as fetched and returns them; SKIP LOCKED keeps
+// concurrent claimers from getting the same feed. A feed with next_fetch_at
+// set is due at that time instead of after the usual interval. Feeds are
+// stamped with when the claiming pass started, so the ones claimed late in a
+// pass are still due in the next.
+func (q *Queries) ClaimFeedsToFetch(ctx context.Context, arg ClaimFeedsToFetchParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, claimFeedsToFetch, arg.PassAgeSecs, arg.IntervalSecs, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.KeepEpisodes,
+			&i.Encoding,
+			&i.SiteUrl,
+			&i.Description,
+			&i.Language,
+			&i.ImageUrl,
+			&i.Generator,
+			&i.LastBuildDate,
+			&i.Etag,
+			&i.LastModified,
+			&i.NextFetchAt,
+			&i.ConsecutiveFailures,
+			&i.LastSuccessAt,
+			&i.LastError,
+			&i.Disabled,
+			&i.RetiredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
 VALUES (
@@ -22,7 +96,7 @@ VALUES (
     $5,
     $6
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, keep_episodes, encoding, site_url, description, language, image_url, generator, last_build_date, etag, last_modified, next_fetch_at, consecutive_failures, last_success_at, last_error, disabled, retired_at
 `
 
 type CreateFeedParams struct {
@@ -52,38 +126,47 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.KeepEpisodes,
+		&i.Encoding,
+		&i.SiteUrl,
+		&i.Description,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
+		&i.LastBuildDate,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextFetchAt,
+		&i.ConsecutiveFailures,
+		&i.LastSuccessAt,
+		&i.LastError,
+		&i.Disabled,
+		&i.RetiredAt,
 	)
 	return i, err
 }
 
-const getFeedByUrl = `-- name: GetFeedByUrl :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
-WHERE url = $1
+const enableFeed = `-- name: EnableFeed :exec
+UPDATE feeds
+SET disabled = FALSE, retired_at = NULL, consecutive_failures = 0, next_fetch_at = NULL, updated_at = NOW()
+WHERE id = $1
 `
 
-func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getFeedByUrl, url)
-	var i Feed
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Name,
-		&i.Url,
-		&i.UserID,
-		&i.LastFetchedAt,
-	)
-	return i, err
+func (q *Queries) EnableFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, enableFeed, id)
+	return err
 }
 
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
-ORDER BY last_fetched_at ASC NULLS FIRST
+const getFeedByUrl = `-- name: GetFeedByUrl :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, keep_episodes, encoding, site_url, description, language, image_url, generator, last_build_date, etag, last_modified, next_fetch_at, consecutive_failures, last_success_at, last_error, disabled, retired_at FROM feeds
+WHERE url = $1
+   OR id = (SELECT feed_id FROM feed_url_history WHERE feed_url_history.url = $1)
+ORDER BY url = $1 DESC
 LIMIT 1
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
+func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByUrl, url)
 	var i Feed
 	err := row.Scan(
 		&i.ID,
@@ -93,6 +176,22 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.KeepEpisodes,
+		&i.Encoding,
+		&i.SiteUrl,
+		&i.Description,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
+		&i.LastBuildDate,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextFetchAt,
+		&i.ConsecutiveFailures,
+		&i.LastSuccessAt,
+		&i.LastError,
+		&i.Disabled,
+		&i.RetiredAt,
 	)
 	return i, err
 }
@@ -104,18 +203,30 @@ SELECT
     feeds.updated_at,
     feeds.name AS feed_name,
     feeds.url,
+    feeds.site_url,
+    feeds.description,
+    feeds.language,
+    feeds.image_url,
+    feeds.generator,
+    feeds.last_build_date,
     users.name AS user_name
 FROM feeds
 JOIN users ON feeds.user_id = users.id
 `
 
 type ListAllFeedsRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	FeedName  string
-	Url       string
-	UserName  string
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	FeedName      string
+	Url           string
+	SiteUrl       sql.NullString
+	Description   sql.NullString
+	Language      sql.NullString
+	ImageUrl      sql.NullString
+	Generator     sql.NullString
+	LastBuildDate sql.NullTime
+	UserName      string
 }
 
 func (q *Queries) ListAllFeeds(ctx context.Context) ([]ListAllFeedsRow, error) {
@@ -133,6 +244,12 @@ func (q *Queries) ListAllFeeds(ctx context.Context) ([]ListAllFeedsRow, error) {
 			&i.UpdatedAt,
 			&i.FeedName,
 			&i.Url,
+			&i.SiteUrl,
+			&i.Description,
+			&i.Language,
+			&i.ImageUrl,
+			&i.Generator,
+			&i.LastBuildDate,
 			&i.UserName,
 		); err != nil {
 			return nil, err
@@ -148,6 +265,59 @@ func (q *Queries) ListAllFeeds(ctx context.Context) ([]ListAllFeedsRow, error) {
 	return items, nil
 }
 
+const listBrokenFeeds = `-- name: ListBrokenFeeds :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, keep_episodes, encoding, site_url, description, language, image_url, generator, last_build_date, etag, last_modified, next_fetch_at, consecutive_failures, last_success_at, last_error, disabled, retired_at FROM feeds
+WHERE disabled OR retired_at IS NOT NULL OR consecutive_failures > 0
+ORDER BY retired_at IS NOT NULL DESC, disabled DESC, consecutive_failures DESC, name
+`
+
+func (q *Queries) ListBrokenFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, listBrokenFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.KeepEpisodes,
+			&i.Encoding,
+			&i.SiteUrl,
+			&i.Description,
+			&i.Language,
+			&i.ImageUrl,
+			&i.Generator,
+			&i.LastBuildDate,
+			&i.Etag,
+			&i.LastModified,
+			&i.NextFetchAt,
+			&i.ConsecutiveFailures,
+			&i.LastSuccessAt,
+			&i.LastError,
+			&i.Disabled,
+			&i.RetiredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const markFeedFetched = `-- name: MarkFeedFetched :exec
 UPDATE feeds
 SET last_fetched_at = NOW(), updated_at = NOW()
@@ -158,3 +328,160 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
 	_, err := q.db.ExecContext(ctx, markFeedFetched, id)
 	return err
 }
+
+const recordFeedFailure = `-- name: RecordFeedFailure :exec
+UPDATE feeds
+SET consecutive_failures = $1,
+    next_fetch_at = NOW() + make_interval(secs => $2),
+    last_error = $3,
+    disabled = $4,
+    updated_at = NOW()
+WHERE id = $5
+`
+
+type RecordFeedFailureParams struct {
+	ConsecutiveFailures int32
+	RetryDelaySecs      float64
+	LastError           sql.NullString
+	Disabled            bool
+	ID                  uuid.UUID
+}
+
+// The retry is scheduled by the database's clock, like SetFeedNextFetch
+func (q *Queries) RecordFeedFailure(ctx context.Context, arg RecordFeedFailureParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedFailure,
+		arg.ConsecutiveFailures,
+		arg.RetryDelaySecs,
+		arg.LastError,
+		arg.Disabled,
+		arg.ID,
+	)
+	return err
+}
+
+const recordFeedSuccess = `-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET consecutive_failures = 0, last_success_at = NOW(), last_error = NULL, updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) RecordFeedSuccess(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, recordFeedSuccess, id)
+	return err
+}
+
+const retireFeed = `-- name: RetireFeed :exec
+UPDATE feeds
+SET retired_at = NOW(), updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) RetireFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, retireFeed, id)
+	return err
+}
+
+const setFeedCacheHeaders = `-- name: SetFeedCacheHeaders :exec
+UPDATE feeds
+SET etag = $2, last_modified = $3, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetFeedCacheHeadersParams struct {
+	ID           uuid.UUID
+	Etag         sql.NullString
+	LastModified sql.NullString
+}
+
+func (q *Queries) SetFeedCacheHeaders(ctx context.Context, arg SetFeedCacheHeadersParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedCacheHeaders, arg.ID, arg.Etag, arg.LastModified)
+	return err
+}
+
+const setFeedKeepEpisodes = `-- name: SetFeedKeepEpisodes :exec
+UPDATE feeds
+SET keep_episodes = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetFeedKeepEpisodesParams struct {
+	ID           uuid.UUID
+	KeepEpisodes sql.NullInt32
+}
+
+func (q *Queries) SetFeedKeepEpisodes(ctx context.Context, arg SetFeedKeepEpisodesParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedKeepEpisodes, arg.ID, arg.KeepEpisodes)
+	return err
+}
+
+const setFeedNextFetch = `-- name: SetFeedNextFetch :exec
+UPDATE feeds
+SET next_fetch_at = NOW() + make_interval(secs => $1), updated_at = NOW()
+WHERE id = $2
+`
+
+type SetFeedNextFetchParams struct {
+	DelaySecs float64
+	ID        uuid.UUID
+}
+
+// Schedules the feed's next fetch delay_secs from now by the database's clock,
+// which is the one ClaimFeedsToFetch compares next_fetch_at against
+func (q *Queries) SetFeedNextFetch(ctx context.Context, arg SetFeedNextFetchParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedNextFetch, arg.DelaySecs, arg.ID)
+	return err
+}
+
+const updateFeedMetadata = `-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET site_url = $2,
+    description = $3,
+    language = $4,
+    image_url = $5,
+    generator = $6,
+    last_build_date = $7,
+    encoding = $8,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateFeedMetadataParams struct {
+	ID            uuid.UUID
+	SiteUrl       sql.NullString
+	Description   sql.NullString
+	Language      sql.NullString
+	ImageUrl      sql.NullString
+	Generator     sql.NullString
+	LastBuildDate sql.NullTime
+	Encoding      sql.NullString
+}
+
+func (q *Queries) UpdateFeedMetadata(ctx context.Context, arg UpdateFeedMetadataParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedMetadata,
+		arg.ID,
+		arg.SiteUrl,
+		arg.Description,
+		arg.Language,
+		arg.ImageUrl,
+		arg.Generator,
+		arg.LastBuildDate,
+		arg.Encoding,
+	)
+	return err
+}
+
+const updateFeedUrl = `-- name: UpdateFeedUrl :exec
+UPDATE feeds
+SET url = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateFeedUrlParams struct {
+	ID  uuid.UUID
+	Url string
+}
+
+func (q *Queries) UpdateFeedUrl(ctx context.Context, arg UpdateFeedUrlParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedUrl, arg.ID, arg.Url)
+	return err
+}
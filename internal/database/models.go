@@ -11,14 +11,63 @@ import (
 	"github.com/google/uuid"
 )
 
+type Download struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	EnclosureID uuid.UUID
+	Path        string
+	Size        int64
+}
+
+type Enclosure struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	PostID          uuid.UUID
+	Url             string
+	MimeType        sql.NullString
+	Length          sql.NullInt64
+	DurationSeconds sql.NullInt32
+	Episode         sql.NullInt32
+	Season          sql.NullInt32
+	ImageUrl        sql.NullString
+}
+
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	LastFetchedAt       sql.NullTime
+	KeepEpisodes        sql.NullInt32
+	Encoding            sql.NullString
+	SiteUrl             sql.NullString
+	Description         sql.NullString
+	Language            sql.NullString
+	ImageUrl            sql.NullString
+	Generator           sql.NullString
+	LastBuildDate       sql.NullTime
+	Etag                sql.NullString
+	LastModified        sql.NullString
+	NextFetchAt         sql.NullTime
+	ConsecutiveFailures int32
+	LastSuccessAt       sql.NullTime
+	LastError           sql.NullString
+	Disabled            bool
+	RetiredAt           sql.NullTime
+}
+
+type FeedFetchSetting struct {
+	FeedID          uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Headers         []byte
+	AuthType        sql.NullString
+	AuthCredentials []byte
+	Proxy           []byte
 }
 
 type FeedFollow struct {
@@ -29,6 +78,26 @@ type FeedFollow struct {
 	FeedID    uuid.UUID
 }
 
+type FeedUrlHistory struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	FeedID    uuid.UUID
+	Url       string
+}
+
+type MediaItem struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	PostID       uuid.UUID
+	Url          string
+	Medium       sql.NullString
+	MimeType     sql.NullString
+	ThumbnailUrl sql.NullString
+	Width        sql.NullInt32
+	Height       sql.NullInt32
+}
+
 type Post struct {
 	ID          uuid.UUID
 	CreatedAt   time.Time
@@ -38,6 +107,25 @@ type Post struct {
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Content     sql.NullString
+	Guid        string
+}
+
+type PostAuthor struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	PostID    uuid.UUID
+	Name      string
+	Email     sql.NullString
+}
+
+type PostCategory struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	PostID    uuid.UUID
+	Name      string
 }
 
 type User struct {
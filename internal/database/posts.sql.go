@@ -13,8 +13,31 @@ import (
 	"github.com/google/uuid"
 )
 
+const adoptLegacyPost = `-- name: AdoptLegacyPost :one
+UPDATE posts
+SET guid = $1, updated_at = NOW()
+WHERE feed_id = $2 AND url = $3 AND guid = url
+RETURNING id
+`
+
+type AdoptLegacyPostParams struct {
+	Guid   string
+	FeedID uuid.UUID
+	Url    string
+}
+
+// Posts saved before they had a guid were given their URL as one. When an
+// item turns out to have a different guid, the old post takes it over instead
+// of the item being saved a second time.
+func (q *Queries) AdoptLegacyPost(ctx context.Context, arg AdoptLegacyPostParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, adoptLegacyPost, arg.Guid, arg.FeedID, arg.Url)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
 const createPost = `-- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, content, guid)
 VALUES (
     $1,
     $2,
@@ -23,9 +46,11 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10
 )
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, content, guid
 `
 
 type CreatePostParams struct {
@@ -37,6 +62,8 @@ type CreatePostParams struct {
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Content     sql.NullString
+	Guid        string
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -49,6 +76,8 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.Content,
+		arg.Guid,
 	)
 	var i Post
 	err := row.Scan(
@@ -60,6 +89,186 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Content,
+		&i.Guid,
+	)
+	return i, err
+}
+
+const createPostAuthor = `-- name: CreatePostAuthor :exec
+INSERT INTO post_authors (id, created_at, updated_at, post_id, name, email)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+ON CONFLICT (post_id, name) DO NOTHING
+`
+
+type CreatePostAuthorParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	PostID    uuid.UUID
+	Name      string
+	Email     sql.NullString
+}
+
+func (q *Queries) CreatePostAuthor(ctx context.Context, arg CreatePostAuthorParams) error {
+	_, err := q.db.ExecContext(ctx, createPostAuthor,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.PostID,
+		arg.Name,
+		arg.Email,
+	)
+	return err
+}
+
+const createPostCategory = `-- name: CreatePostCategory :exec
+INSERT INTO post_categories (id, created_at, updated_at, post_id, name)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+ON CONFLICT (post_id, name) DO NOTHING
+`
+
+type CreatePostCategoryParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	PostID    uuid.UUID
+	Name      string
+}
+
+func (q *Queries) CreatePostCategory(ctx context.Context, arg CreatePostCategoryParams) error {
+	_, err := q.db.ExecContext(ctx, createPostCategory,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.PostID,
+		arg.Name,
+	)
+	return err
+}
+
+const getAuthorsForPost = `-- name: GetAuthorsForPost :many
+SELECT id, created_at, updated_at, post_id, name, email FROM post_authors
+WHERE post_id = $1
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetAuthorsForPost(ctx context.Context, postID uuid.UUID) ([]PostAuthor, error) {
+	rows, err := q.db.QueryContext(ctx, getAuthorsForPost, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PostAuthor
+	for rows.Next() {
+		var i PostAuthor
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.PostID,
+			&i.Name,
+			&i.Email,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCategoriesForPost = `-- name: GetCategoriesForPost :many
+SELECT id, created_at, updated_at, post_id, name FROM post_categories
+WHERE post_id = $1
+ORDER BY name ASC
+`
+
+func (q *Queries) GetCategoriesForPost(ctx context.Context, postID uuid.UUID) ([]PostCategory, error) {
+	rows, err := q.db.QueryContext(ctx, getCategoriesForPost, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PostCategory
+	for rows.Next() {
+		var i PostCategory
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.PostID,
+			&i.Name,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostByID = `-- name: GetPostByID :one
+SELECT
+    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content, posts.guid,
+    feeds.name AS feed_name
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+WHERE posts.id = $1
+`
+
+type GetPostByIDRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Content     sql.NullString
+	Guid        string
+	FeedName    string
+}
+
+func (q *Queries) GetPostByID(ctx context.Context, id uuid.UUID) (GetPostByIDRow, error) {
+	row := q.db.QueryRowContext(ctx, getPostByID, id)
+	var i GetPostByIDRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Content,
+		&i.Guid,
+		&i.FeedName,
 	)
 	return i, err
 }
@@ -74,18 +283,31 @@ SELECT
     posts.description,
     posts.published_at,
     posts.feed_id,
+    posts.content,
     feeds.name AS feed_name
 FROM posts
 JOIN feeds ON posts.feed_id = feeds.id
 JOIN feed_follows ON feed_follows.feed_id = feeds.id
 WHERE feed_follows.user_id = $1
+  AND ($2::text IS NULL OR EXISTS (
+      SELECT 1 FROM post_categories
+      WHERE post_categories.post_id = posts.id
+        AND LOWER(post_categories.name) = LOWER($2)
+  ))
+  AND ($3::text IS NULL OR EXISTS (
+      SELECT 1 FROM post_authors
+      WHERE post_authors.post_id = posts.id
+        AND LOWER(post_authors.name) = LOWER($3)
+  ))
 ORDER BY posts.published_at DESC NULLS LAST
-LIMIT $2
+LIMIT $4
 `
 
 type GetPostsForUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+	UserID   uuid.UUID
+	Category sql.NullString
+	Author   sql.NullString
+	Limit    int32
 }
 
 type GetPostsForUserRow struct {
@@ -97,11 +319,17 @@ type GetPostsForUserRow struct {
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Content     sql.NullString
 	FeedName    string
 }
 
 func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.Category,
+		arg.Author,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -118,6 +346,7 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Content,
 			&i.FeedName,
 		); err != nil {
 			return nil, err
@@ -28,8 +28,9 @@ func main() {
 	dbQueries := database.New(db)
 
 	programState := &cli.State{
-		Cfg: &cfg,
-		DB:  dbQueries,
+		Cfg:  &cfg,
+		DB:   dbQueries,
+		Conn: db,
 	}
 
 	cmds := cli.NewCommands()
@@ -39,12 +40,18 @@ func main() {
 	cmds.Register("users", cli.HandlerUsers)
 	cmds.Register("agg", cli.HandlerAgg)
 	cmds.Register("feeds", cli.HandlerListFeeds)
+	cmds.Register("read", cli.HandlerRead)
+	cmds.Register("broken", cli.HandlerBroken)
+	cmds.Register("enable", cli.HandlerEnable)
+	cmds.Register("fetchopts", cli.HandlerFetchOptions)
 
 	cmds.Register("addfeed", cli.MiddlewareLoggedIn(cli.HandlerAddFeed))
 	cmds.Register("follow", cli.MiddlewareLoggedIn(cli.HandlerFollow))
 	cmds.Register("following", cli.MiddlewareLoggedIn(cli.HandlerFollowing))
 	cmds.Register("unfollow", cli.MiddlewareLoggedIn(cli.HandlerUnfollow))
 	cmds.Register("browse", cli.MiddlewareLoggedIn(cli.HandlerBrowse))
+	cmds.Register("download", cli.MiddlewareLoggedIn(cli.HandlerDownload))
+	cmds.Register("keep", cli.MiddlewareLoggedIn(cli.HandlerKeep))
 
 	args := os.Args
 	if len(args) < 2 {
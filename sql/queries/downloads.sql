@@ -0,0 +1,64 @@
+-- name: CreateDownload :one
+INSERT INTO downloads (id, created_at, updated_at, enclosure_id, path, size)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+RETURNING *;
+
+-- name: DeleteDownload :exec
+DELETE FROM downloads
+WHERE id = $1;
+
+-- name: GetEpisodesToDownload :many
+WITH ranked AS (
+    SELECT
+        enclosures.id AS enclosure_id,
+        enclosures.url,
+        enclosures.mime_type,
+        posts.title AS post_title,
+        feeds.name AS feed_name,
+        posts.feed_id,
+        feeds.url AS feed_url,
+        feeds.keep_episodes,
+        DENSE_RANK() OVER (
+            PARTITION BY posts.feed_id
+            ORDER BY posts.published_at DESC NULLS LAST, posts.id
+        ) AS episode_rank
+    FROM enclosures
+    JOIN posts ON enclosures.post_id = posts.id
+    JOIN feeds ON posts.feed_id = feeds.id
+    JOIN feed_follows ON feed_follows.feed_id = feeds.id
+    WHERE feed_follows.user_id = $1
+)
+SELECT ranked.*
+FROM ranked
+LEFT JOIN downloads ON downloads.enclosure_id = ranked.enclosure_id
+WHERE downloads.id IS NULL
+  AND (ranked.keep_episodes IS NULL OR ranked.episode_rank <= ranked.keep_episodes)
+ORDER BY ranked.feed_name, ranked.episode_rank;
+
+-- name: GetDownloadsToPrune :many
+WITH ranked AS (
+    SELECT
+        enclosures.id AS enclosure_id,
+        feeds.keep_episodes,
+        DENSE_RANK() OVER (
+            PARTITION BY posts.feed_id
+            ORDER BY posts.published_at DESC NULLS LAST, posts.id
+        ) AS episode_rank
+    FROM enclosures
+    JOIN posts ON enclosures.post_id = posts.id
+    JOIN feeds ON posts.feed_id = feeds.id
+    JOIN feed_follows ON feed_follows.feed_id = feeds.id
+    WHERE feed_follows.user_id = $1
+)
+SELECT downloads.*
+FROM downloads
+JOIN ranked ON downloads.enclosure_id = ranked.enclosure_id
+WHERE ranked.keep_episodes IS NOT NULL
+  AND ranked.episode_rank > ranked.keep_episodes;
@@ -0,0 +1,21 @@
+-- name: CreateEnclosure :one
+INSERT INTO enclosures (id, created_at, updated_at, post_id, url, mime_type, length, duration_seconds, episode, season, image_url)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10,
+    $11
+)
+RETURNING *;
+
+-- name: GetEnclosuresForPost :many
+SELECT * FROM enclosures
+WHERE post_id = $1
+ORDER BY created_at ASC;
@@ -0,0 +1,23 @@
+-- name: DeleteFeedFetchSettings :exec
+DELETE FROM feed_fetch_settings WHERE feed_id = $1;
+
+-- name: GetFeedFetchSettings :one
+SELECT * FROM feed_fetch_settings WHERE feed_id = $1;
+
+-- name: SetFeedFetchSettings :exec
+INSERT INTO feed_fetch_settings (feed_id, created_at, updated_at, headers, auth_type, auth_credentials, proxy)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7
+)
+ON CONFLICT (feed_id) DO UPDATE SET
+    updated_at = EXCLUDED.updated_at,
+    headers = EXCLUDED.headers,
+    auth_type = EXCLUDED.auth_type,
+    auth_credentials = EXCLUDED.auth_credentials,
+    proxy = EXCLUDED.proxy;
@@ -13,6 +13,11 @@ JOIN users u ON inserted.user_id = u.id;
 SELECT
     ff.*,
     feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    feeds.site_url,
+    feeds.description,
+    feeds.language,
+    feeds.last_build_date,
     users.name AS user_name
 FROM feed_follows ff
 JOIN feeds  ON ff.feed_id   = feeds.id
@@ -20,7 +25,5 @@ JOIN users  ON ff.user_id   = users.id
 WHERE ff.user_id = $1;
 
 -- name: DeleteFeedFollow :exec
-DELETE FROM feed_follows 
-WHERE feed_follows.user_id = $1 AND feed_follows.feed_id = (
-    SELECT id FROM feeds WHERE url = $2
-);
+DELETE FROM feed_follows
+WHERE user_id = $1 AND feed_id = $2;
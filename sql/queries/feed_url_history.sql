@@ -0,0 +1,9 @@
+-- name: AddFeedUrlHistory :exec
+INSERT INTO feed_url_history (id, created_at, feed_id, url)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (url) DO NOTHING;
@@ -17,20 +17,107 @@ SELECT
     feeds.updated_at,
     feeds.name AS feed_name,
     feeds.url,
+    feeds.site_url,
+    feeds.description,
+    feeds.language,
+    feeds.image_url,
+    feeds.generator,
+    feeds.last_build_date,
     users.name AS user_name
 FROM feeds
 JOIN users ON feeds.user_id = users.id;
 
 -- name: GetFeedByUrl :one
 SELECT * FROM feeds
-WHERE url = $1;
+WHERE url = $1
+   OR id = (SELECT feed_id FROM feed_url_history WHERE feed_url_history.url = $1)
+ORDER BY url = $1 DESC
+LIMIT 1;
 
 -- name: MarkFeedFetched :exec
 UPDATE feeds
 SET last_fetched_at = NOW(), updated_at = NOW()
 WHERE id = $1;
 
--- name: GetNextFeedToFetch :one
+-- name: ClaimFeedsToFetch :many
+-- Marks a batch of due feeds as fetched and returns them; SKIP LOCKED keeps
+-- concurrent claimers from getting the same feed. A feed with next_fetch_at
+-- set is due at that time instead of after the usual interval. Feeds are
+-- stamped with when the claiming pass started, so the ones claimed late in a
+-- pass are still due in the next.
+UPDATE feeds
+SET last_fetched_at = NOW() - make_interval(secs => sqlc.arg(pass_age_secs)), next_fetch_at = NULL, updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM feeds
+    WHERE NOT disabled AND retired_at IS NULL
+      AND ((next_fetch_at IS NULL AND (last_fetched_at IS NULL OR last_fetched_at < NOW() - make_interval(secs => sqlc.arg(interval_secs))))
+        OR next_fetch_at <= NOW())
+    ORDER BY last_fetched_at ASC NULLS FIRST
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: RecordFeedFailure :exec
+-- The retry is scheduled by the database's clock, like SetFeedNextFetch
+UPDATE feeds
+SET consecutive_failures = sqlc.arg(consecutive_failures),
+    next_fetch_at = NOW() + make_interval(secs => sqlc.arg(retry_delay_secs)),
+    last_error = sqlc.arg(last_error),
+    disabled = sqlc.arg(disabled),
+    updated_at = NOW()
+WHERE id = sqlc.arg(id);
+
+-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET consecutive_failures = 0, last_success_at = NOW(), last_error = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetFeedCacheHeaders :exec
+UPDATE feeds
+SET etag = $2, last_modified = $3, updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetFeedNextFetch :exec
+-- Schedules the feed's next fetch delay_secs from now by the database's clock,
+-- which is the one ClaimFeedsToFetch compares next_fetch_at against
+UPDATE feeds
+SET next_fetch_at = NOW() + make_interval(secs => sqlc.arg(delay_secs)), updated_at = NOW()
+WHERE id = sqlc.arg(id);
+
+-- name: SetFeedKeepEpisodes :exec
+UPDATE feeds
+SET keep_episodes = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET site_url = $2,
+    description = $3,
+    language = $4,
+    image_url = $5,
+    generator = $6,
+    last_build_date = $7,
+    encoding = $8,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: ListBrokenFeeds :many
 SELECT * FROM feeds
-ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1;
\ No newline at end of file
+WHERE disabled OR retired_at IS NOT NULL OR consecutive_failures > 0
+ORDER BY retired_at IS NOT NULL DESC, disabled DESC, consecutive_failures DESC, name;
+
+-- name: EnableFeed :exec
+UPDATE feeds
+SET disabled = FALSE, retired_at = NULL, consecutive_failures = 0, next_fetch_at = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: RetireFeed :exec
+UPDATE feeds
+SET retired_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateFeedUrl :exec
+UPDATE feeds
+SET url = $2, updated_at = NOW()
+WHERE id = $1;
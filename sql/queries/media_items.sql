@@ -0,0 +1,20 @@
+-- name: CreateMediaItem :exec
+INSERT INTO media_items (id, created_at, updated_at, post_id, url, medium, mime_type, thumbnail_url, width, height)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10
+)
+ON CONFLICT (post_id, url) DO NOTHING;
+
+-- name: GetMediaItemsForPost :many
+SELECT * FROM media_items
+WHERE post_id = $1
+ORDER BY created_at ASC;
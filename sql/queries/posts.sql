@@ -1,5 +1,5 @@
 -- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, content, guid)
 VALUES (
     $1,
     $2,
@@ -8,7 +8,9 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10
 )
 RETURNING *;
 
@@ -22,10 +24,71 @@ SELECT
     posts.description,
     posts.published_at,
     posts.feed_id,
+    posts.content,
     feeds.name AS feed_name
 FROM posts
 JOIN feeds ON posts.feed_id = feeds.id
 JOIN feed_follows ON feed_follows.feed_id = feeds.id
-WHERE feed_follows.user_id = $1
+WHERE feed_follows.user_id = sqlc.arg('user_id')
+  AND (sqlc.narg('category')::text IS NULL OR EXISTS (
+      SELECT 1 FROM post_categories
+      WHERE post_categories.post_id = posts.id
+        AND LOWER(post_categories.name) = LOWER(sqlc.narg('category'))
+  ))
+  AND (sqlc.narg('author')::text IS NULL OR EXISTS (
+      SELECT 1 FROM post_authors
+      WHERE post_authors.post_id = posts.id
+        AND LOWER(post_authors.name) = LOWER(sqlc.narg('author'))
+  ))
 ORDER BY posts.published_at DESC NULLS LAST
-LIMIT $2; 
\ No newline at end of file
+LIMIT sqlc.arg('limit');
+
+-- name: GetPostByID :one
+SELECT
+    posts.*,
+    feeds.name AS feed_name
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+WHERE posts.id = $1;
+
+-- name: CreatePostAuthor :exec
+INSERT INTO post_authors (id, created_at, updated_at, post_id, name, email)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+ON CONFLICT (post_id, name) DO NOTHING;
+
+-- name: CreatePostCategory :exec
+INSERT INTO post_categories (id, created_at, updated_at, post_id, name)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+ON CONFLICT (post_id, name) DO NOTHING;
+
+-- name: GetAuthorsForPost :many
+SELECT * FROM post_authors
+WHERE post_id = $1
+ORDER BY created_at ASC;
+
+-- name: GetCategoriesForPost :many
+SELECT * FROM post_categories
+WHERE post_id = $1
+ORDER BY name ASC;
+
+-- name: AdoptLegacyPost :one
+-- Posts saved before they had a guid were given their URL as one. When an
+-- item turns out to have a different guid, the old post takes it over instead
+-- of the item being saved a second time.
+UPDATE posts
+SET guid = sqlc.arg(guid), updated_at = NOW()
+WHERE feed_id = sqlc.arg(feed_id) AND url = sqlc.arg(url) AND guid = url
+RETURNING id;
@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE enclosures (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    post_id UUID NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    mime_type TEXT,
+    length BIGINT,
+    duration_seconds INTEGER,
+    episode INTEGER,
+    season INTEGER,
+    image_url TEXT,
+    UNIQUE (post_id, url)
+);
+
+-- +goose Down
+DROP TABLE enclosures;
@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN keep_episodes INTEGER;
+
+CREATE TABLE downloads (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    enclosure_id UUID NOT NULL UNIQUE REFERENCES enclosures (id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    size BIGINT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE downloads;
+ALTER TABLE feeds DROP COLUMN keep_episodes;
@@ -0,0 +1,26 @@
+-- +goose Up
+CREATE TABLE post_authors (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    post_id UUID NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    email TEXT,
+    UNIQUE (post_id, name)
+);
+
+CREATE TABLE post_categories (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    post_id UUID NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    UNIQUE (post_id, name)
+);
+
+CREATE INDEX post_authors_name_idx ON post_authors (LOWER(name));
+CREATE INDEX post_categories_name_idx ON post_categories (LOWER(name));
+
+-- +goose Down
+DROP TABLE post_categories;
+DROP TABLE post_authors;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN encoding TEXT;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN encoding;
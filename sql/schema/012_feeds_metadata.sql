@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE feeds
+    ADD COLUMN site_url TEXT,
+    ADD COLUMN description TEXT,
+    ADD COLUMN language TEXT,
+    ADD COLUMN image_url TEXT,
+    ADD COLUMN generator TEXT,
+    ADD COLUMN last_build_date TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE feeds
+    DROP COLUMN site_url,
+    DROP COLUMN description,
+    DROP COLUMN language,
+    DROP COLUMN image_url,
+    DROP COLUMN generator,
+    DROP COLUMN last_build_date;
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE media_items (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    post_id UUID NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    medium TEXT,
+    mime_type TEXT,
+    thumbnail_url TEXT,
+    width INTEGER,
+    height INTEGER,
+    UNIQUE (post_id, url)
+);
+
+-- +goose Down
+DROP TABLE media_items;
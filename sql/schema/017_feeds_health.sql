@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE feeds
+    ADD COLUMN last_success_at TIMESTAMP,
+    ADD COLUMN last_error TEXT,
+    ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE feeds
+    DROP COLUMN last_success_at,
+    DROP COLUMN last_error,
+    DROP COLUMN disabled;
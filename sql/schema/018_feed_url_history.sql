@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE feed_url_history (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    feed_id UUID NOT NULL REFERENCES feeds (id) ON DELETE CASCADE,
+    url TEXT NOT NULL UNIQUE
+);
+
+ALTER TABLE feeds ADD COLUMN retired_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN retired_at;
+
+DROP TABLE feed_url_history;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- headers, auth_credentials and proxy are encrypted with the config's secret_key
+CREATE TABLE feed_fetch_settings (
+    feed_id UUID PRIMARY KEY REFERENCES feeds (id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    headers BYTEA,
+    auth_type TEXT,
+    auth_credentials BYTEA,
+    proxy BYTEA
+);
+
+-- +goose Down
+DROP TABLE feed_fetch_settings;